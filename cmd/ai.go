@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/saint/ghquick/internal/ai"
+	"github.com/saint/ghquick/internal/config"
+	"github.com/saint/ghquick/internal/git"
+)
+
+// historySize is how many recent commits are read to learn the style
+const historySize = 30
+
+// newCommitGenerator builds a generator for the configured AI provider
+func newCommitGenerator(cfg *config.Config, opts ai.Options) (*ai.CommitMessageGenerator, error) {
+	gen, err := ai.NewMessageGenerator(ai.ProviderConfig{
+		Provider:   cfg.AIProvider,
+		APIKey:     cfg.AIAPIKey(),
+		BaseURL:    cfg.AIBaseURL,
+		Model:      cfg.AIModel,
+		APIVersion: cfg.AzureAPIVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up AI provider: %w", err)
+	}
+	return ai.NewCommitMessageGenerator(gen, opts), nil
+}
+
+// baseOptions are the generator options that come from config alone
+func baseOptions(cfg *config.Config) ai.Options {
+	return ai.Options{
+		Diff: ai.DiffOptions{
+			MaxTokens: cfg.AIDiffTokens,
+			Mode:      ai.DiffMode(cfg.AIDiffMode),
+		},
+		Format: ai.MessageFormat(cfg.CommitFormat),
+		Rules: ai.Rules{
+			Types:  cfg.CommitTypes,
+			Scopes: cfg.CommitScopes,
+		},
+	}
+}
+
+// commitOptions adds what commit messages need from the repository: its
+// style and prompt template
+func commitOptions(ctx context.Context, cfg *config.Config, gitOps *git.Operations) (ai.Options, error) {
+	opts := baseOptions(cfg)
+	opts.Style = commitStyle(ctx, cfg, gitOps)
+	tmpl, err := commitTemplate(ctx, cfg, gitOps)
+	if err != nil {
+		return ai.Options{}, err
+	}
+	opts.Template = tmpl
+	return opts, nil
+}
+
+// commitTemplate loads the prompt template named by commit_template, or
+// the repository's .ghquick/prompts/commit.tmpl if there is one. It
+// returns nil to use the built-in prompt.
+func commitTemplate(ctx context.Context, cfg *config.Config, gitOps *git.Operations) (*ai.PromptTemplate, error) {
+	path, err := repoFile(ctx, cfg, gitOps, config.KeyCommitTemplate, ai.DefaultPromptFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := ai.LoadPromptTemplate(path)
+	if err != nil {
+		if cfg.CommitTemplate == "" && errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	logger.Debug("Using prompt template %s", path)
+	return tmpl, nil
+}
+
+// commitStyle learns the repository's commit conventions from its recent
+// history and the branch name. Failing to read them only costs examples.
+func commitStyle(ctx context.Context, cfg *config.Config, gitOps *git.Operations) ai.Style {
+	subjects, err := gitOps.RecentSubjects(ctx, historySize)
+	if err != nil {
+		logger.Debug("Not using commit history for style: %v", err)
+	}
+	branch, err := gitOps.CurrentBranch(ctx)
+	if err != nil {
+		logger.Debug("Not using branch name for style: %v", err)
+	}
+
+	style := ai.DetectStyle(subjects, branch, ai.Rules{Types: cfg.CommitTypes})
+	switch cfg.CommitStyle {
+	case "conventional":
+		style.FreeForm = false
+	case "free-form":
+		style.FreeForm = true
+	}
+	if style.Ticket != "" {
+		logger.Debug("Referencing ticket %s from branch %s", style.Ticket, branch)
+	}
+	return style
+}
+
+// commitTrailers holds the --trailer flags, e.g. "Co-authored-by: Name <email>"
+var commitTrailers []string
+
+// withTrailers appends the --trailer flags to message
+func withTrailers(message string) (string, error) {
+	if len(commitTrailers) == 0 {
+		return message, nil
+	}
+	msg, err := ai.ParseCommitMessage(message)
+	if err != nil {
+		return "", err
+	}
+	for _, arg := range commitTrailers {
+		t, err := ai.ParseTrailer(arg)
+		if err != nil {
+			return "", fmt.Errorf("--trailer: %w", err)
+		}
+		msg.AddTrailer(t.Token, t.Value)
+	}
+	return msg.String(), nil
+}
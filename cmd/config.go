@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/saint/ghquick/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// flagKeys maps command-line flags onto the config keys they override
+var flagKeys = map[string]string{
+	"timeout":       config.KeyTimeout,
+	"visibility":    config.KeyVisibility,
+	"commit-format": config.KeyCommitFormat,
+	"candidates":    config.KeyAICandidates,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+}
+
+// loadConfig resolves the layered config for the working directory and
+// applies any flags the user explicitly set on cmd
+func loadConfig(cmd *cobra.Command, wd string) (*config.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	cfg, err := config.Load(path, wd)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, key := range flagKeys {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || !f.Changed {
+			continue
+		}
+		if err := cfg.Set(key, f.Value.String(), config.SourceFlag); err != nil {
+			return nil, fmt.Errorf("--%s: %w", name, err)
+		}
+	}
+
+	if f := cmd.Flags().Lookup("private"); f != nil && f.Changed {
+		visibility := "public"
+		if f.Value.String() == "true" {
+			visibility = "private"
+		}
+		if err := cfg.Set(config.KeyVisibility, visibility, config.SourceFlag); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect ghquick configuration",
+	Long: `Inspect the resolved ghquick configuration.
+
+Values are layered, later sources winning:
+  1. built-in defaults
+  2. global file ($HOME/.ghquick.yaml or --config)
+  3. per-repo .ghquick.yaml at the root of the git work tree
+  4. environment variables (GITHUB_TOKEN, GITHUB_USERNAME, OPENAI_API_KEY, GHQUICK_<KEY>)
+  5. command-line flags`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the resolved configuration with secrets masked",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+
+		cfg, err := loadConfig(cmd, wd)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		out := cmd.OutOrStdout()
+		if len(cfg.Files) == 0 {
+			fmt.Fprintln(out, "# no config files found")
+		}
+		for _, file := range cfg.Files {
+			fmt.Fprintf(out, "# loaded %s\n", file)
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, key := range config.Keys {
+			value := cfg.Masked(key)
+			source := cfg.Sources[key]
+			if value == "" {
+				value = "(not set)"
+				source = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, source)
+		}
+		return w.Flush()
+	},
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/saint/ghquick/internal/git"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(credentialCmd)
+}
+
+// credentialHelper returns a git credential.helper value that calls back
+// into this binary, forwarding --config so the helper resolves the same
+// configuration as the command that spawned git
+func credentialHelper(cmd *cobra.Command) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate ghquick executable: %w", err)
+	}
+
+	helper := "!" + shellQuote(exe) + " credential"
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve config path: %w", err)
+		}
+		helper += " --config " + shellQuote(abs)
+	}
+	return helper, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var credentialCmd = &cobra.Command{
+	Use:   "credential <get|store|erase>",
+	Short: "Git credential helper that serves the configured GitHub token",
+	Long: `Implements the git credential helper protocol so git can authenticate
+against GitHub without the token being written into .git/config.
+
+ghquick push wires this up automatically. To use it for plain git commands:
+  git config --global credential.https://github.com.helper '!ghquick credential'`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"get", "store", "erase"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The token lives in ghquick's config, so there is nothing to
+		// store or erase when git reports success or failure
+		if args[0] != "get" {
+			return nil
+		}
+
+		req, err := git.ReadCredential(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		cfg, err := loadConfig(cmd, wd)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		// Answering with nothing lets git fall through to other helpers.
+		// The token is only ever offered to the configured github_host.
+		if req["protocol"] != "https" || !cfg.TrustsHost(req["host"]) || cfg.GitHubToken == "" {
+			return nil
+		}
+
+		// GitHub ignores the username for token auth, but git wants one
+		username := cfg.GitHubUsername
+		if username == "" {
+			username = "x-access-token"
+		}
+
+		return git.Credential{
+			"protocol": req["protocol"],
+			"host":     req["host"],
+			"username": username,
+			"password": cfg.GitHubToken,
+		}.Write(cmd.OutOrStdout())
+	},
+}
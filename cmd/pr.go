@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/saint/ghquick/internal/config"
+	"github.com/saint/ghquick/internal/git"
+	"github.com/saint/ghquick/internal/github"
+	"github.com/saint/ghquick/internal/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	openPR      bool
+	prBase      string
+	prTitle     string
+	prBody      string
+	prDraft     bool
+	prReviewers []string
+	prLabels    []string
+)
+
+func init() {
+	rootCmd.AddCommand(prCmd)
+
+	prCmd.Flags().StringVar(&repoName, "name", "", "Repository name (defaults to current directory name)")
+	prCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	prCmd.Flags().DurationVar(&timeout, "timeout", timeout, "Timeout for operations")
+	prCmd.Flags().StringVar(&pushBranch, "branch", "", "Remote branch to push to (defaults to the upstream or current branch)")
+	prCmd.Flags().StringVar(&pushRemote, "remote", "", "Remote to push to (defaults to the upstream remote or configured remote)")
+	addPullRequestFlags(prCmd.Flags())
+	addOwnerFlags(prCmd.Flags())
+}
+
+// addPullRequestFlags registers the flags shared by pr and push --pr
+func addPullRequestFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&prBase, "base", "", "Branch to merge into (defaults to the repository's default branch)")
+	fs.StringVar(&prTitle, "title", "", "Pull request title (AI-generated when omitted)")
+	fs.StringVar(&prBody, "body", "", "Pull request body (AI-generated with the title when omitted)")
+	fs.BoolVar(&prDraft, "draft", false, "Open the pull request as a draft")
+	fs.StringSliceVar(&prReviewers, "reviewer", nil, "Request review from a user or org/team (repeatable)")
+	fs.StringSliceVar(&prLabels, "label", nil, "Add a label (repeatable)")
+}
+
+// pullRequestBase returns the branch a pull request merges into: --base,
+// then the repository's default branch, then the configured one. It fails
+// when that is the branch being pushed, so callers can check before
+// anything is committed or pushed.
+func pullRequestBase(cfg *config.Config, repo *github.Repo, target *git.PushTarget) (string, error) {
+	base := prBase
+	if base == "" {
+		base = repo.DefaultBranch
+	}
+	if base == "" {
+		base = cfg.DefaultBranch
+	}
+	if base == target.Branch {
+		return "", fmt.Errorf("cannot open a pull request from %s into itself; push a feature branch or pass --base", base)
+	}
+	return base, nil
+}
+
+// openPullRequest opens a pull request from the pushed target branch into
+// base, or reports the open one that already exists for it
+func openPullRequest(ctx context.Context, cfg *config.Config, gitOps *git.Operations, ghClient *github.Client, repo *github.Repo, target *git.PushTarget, base string) error {
+
+	logger.Step("Checking for an open pull request...")
+	existing, err := ghClient.FindOpenPullRequest(ctx, repo.Owner, repo.Name, target.Branch)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		logger.Success("Pull request #%d is already open: %s", existing.Number, existing.URL)
+		return nil
+	}
+
+	title, body := prTitle, prBody
+	if title == "" {
+		if err := gitOps.FetchBranch(ctx, target.Remote, base); err != nil {
+			return err
+		}
+		baseRef := fmt.Sprintf("%s/%s", target.Remote, base)
+		commits, err := gitOps.CommitLog(ctx, baseRef, target.LocalRef)
+		if err != nil {
+			return err
+		}
+		if commits == "" {
+			return fmt.Errorf("no commits between %s and %s", baseRef, target.LocalRef)
+		}
+		diff, err := gitOps.RangeDiff(ctx, baseRef, target.LocalRef)
+		if err != nil {
+			return err
+		}
+
+		logger.Step("Generating pull request description...")
+		gen, err := newCommitGenerator(cfg, baseOptions(cfg))
+		if err != nil {
+			return err
+		}
+		text, err := gen.GeneratePullRequest(ctx, commits, diff)
+		if err != nil {
+			logger.Error("Failed to generate pull request description")
+			return err
+		}
+		title = text.Title
+		if body == "" {
+			body = text.Body
+		}
+		logger.Success("Pull request title generated: %s", title)
+	}
+
+	pr, err := ghClient.CreatePullRequest(ctx, repo.Owner, repo.Name, github.PullRequestOptions{
+		Title:     title,
+		Body:      body,
+		Head:      target.Branch,
+		Base:      base,
+		Draft:     prDraft,
+		Reviewers: prReviewers,
+		Labels:    prLabels,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Success("🚀 Pull request opened: %s", pr.URL)
+	return nil
+}
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Push the current branch and open a pull request",
+	Long: `Push the current branch and open a pull request for it, with an
+AI-generated title and description unless --title is given. If an open pull
+request already exists for the branch it is reused.
+Example:
+  ghquick pr
+  ghquick pr --base develop --draft --reviewer octocat --label enhancement`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger = log.New(debug)
+
+		wd, err := os.Getwd()
+		if err != nil {
+			logger.Error("Failed to get working directory")
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+
+		logger.Step("Loading configuration...")
+		caps := []config.Capability{config.CapGitHub}
+		if prTitle == "" {
+			caps = append(caps, config.CapAIPullRequest)
+		}
+		cfg, err := loadConfig(cmd, wd)
+		if err == nil {
+			err = cfg.Require(caps...)
+		}
+		if err != nil {
+			logger.Error("Failed to load configuration")
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		logger.Success("Configuration loaded")
+		timeout = cfg.Timeout
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		gitOps := git.NewOperations(wd, debug)
+		target, err := gitOps.ResolvePushTarget(ctx, pushRemote, pushBranch, cfg.Remote)
+		if err != nil {
+			return fmt.Errorf("failed to determine push target: %w", err)
+		}
+
+		remoteURL, err := resolveRepo(ctx, cfg, gitOps, target.Remote, wd, "run ghquick push --fix-remote")
+		if err != nil {
+			return err
+		}
+		ghClient, err := connectGitHub(cmd, cfg, gitOps, remoteURL)
+		if err != nil {
+			return err
+		}
+		if ghClient == nil {
+			return fmt.Errorf("cannot open a pull request on %s: set github_host to use it with ghquick", remoteURL.Host)
+		}
+
+		repo, err := ghClient.GetRepository(ctx, remoteURL.Owner, remoteURL.Repo)
+		if err != nil {
+			return err
+		}
+
+		base, err := pullRequestBase(cfg, repo, target)
+		if err != nil {
+			return err
+		}
+
+		if err := pushWithRetry(ctx, gitOps, target); err != nil {
+			// The repository may have been deleted or moved since it was cached
+			ghClient.ForgetRepo(remoteURL.Owner, remoteURL.Repo)
+			return err
+		}
+		return openPullRequest(ctx, cfg, gitOps, ghClient, repo, target, base)
+	},
+}
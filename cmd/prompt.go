@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// assumeYes skips confirmation prompts, for scripts
+var assumeYes bool
+
+// errNotInteractive is returned when a prompt is needed but stdin is not
+// a terminal
+var errNotInteractive = errors.New("stdin is not a terminal")
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// isInteractive reports whether stdin is a terminal a user can answer on
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// readLine prints prompt and returns the trimmed line the user types
+func readLine(prompt string) (string, error) {
+	if !isInteractive() {
+		return "", errNotInteractive
+	}
+	fmt.Print(prompt)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// confirm asks a yes/no question, defaulting to no. --yes answers yes
+// without asking; without a terminal it fails rather than guessing.
+func confirm(question string) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+	answer, err := readLine(question + " [y/N] ")
+	if err != nil {
+		if errors.Is(err, errNotInteractive) {
+			return false, fmt.Errorf("%s: %w (pass --yes to confirm)", question, err)
+		}
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
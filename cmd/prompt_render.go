@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/saint/ghquick/internal/ai"
+	"github.com/saint/ghquick/internal/git"
+	"github.com/saint/ghquick/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var renderHint string
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+	promptCmd.AddCommand(promptRenderCmd)
+
+	promptRenderCmd.Flags().StringVar(&renderHint, "hint", "", "Render with a regeneration hint")
+}
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Work with AI prompt templates",
+	Long: `Work with the prompt used to generate commit messages.
+
+The commit prompt is a Go text/template. Set commit_template in config, or
+add .ghquick/prompts/commit.tmpl to the repository. The template body is the
+user message; a {{define "system"}}...{{end}} block replaces the system
+prompt. Available fields:
+
+  .Diff          staged diff, fitted to ai_max_diff_tokens
+  .Stat          per-file summary like git diff --stat
+  .Files         changed paths
+  .Branch        current branch
+  .RecentLog     recent commit subjects, newest first
+  .Ticket        ticket key from the branch name, e.g. ABC-123
+  .Scope         scope inferred from the changed paths
+  .Hint          hint given when regenerating
+  .Conventional  whether Conventional Commits rules apply
+  .Types         allowed commit types
+  .Scopes        allowed scopes
+
+Functions: join, lines, trim.`,
+}
+
+var promptRenderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Print the commit prompt for the staged changes",
+	Long: `Print the exact system and user prompt that 'ghquick push start' would
+send for the currently staged changes, without calling the model.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger = log.New(false)
+
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		cfg, err := loadConfig(cmd, wd)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+
+		gitOps := git.NewOperations(wd, false)
+		diff, err := gitOps.StagedDiff(ctx)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			return fmt.Errorf("nothing is staged; stage changes with git add to preview the prompt")
+		}
+
+		opts, err := commitOptions(ctx, cfg, gitOps)
+		if err != nil {
+			return err
+		}
+		if opts.Diff.Mode == ai.DiffModeMapReduce {
+			// Summaries come from the model, which render never calls
+			fmt.Fprintln(os.Stderr, "note: ai_diff_mode is map-reduce; oversized diffs are shown truncated here but would be summarized per file")
+			opts.Diff.Mode = ai.DiffModeTruncate
+		}
+
+		req, err := ai.NewCommitMessageGenerator(nil, opts).RenderPrompt(ctx, diff, renderHint)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("--- system ---\n%s\n\n--- user ---\n%s\n", req.System, req.User)
+		return nil
+	},
+}
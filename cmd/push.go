@@ -2,13 +2,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"os"
-	"path/filepath"
 	"time"
 
-	"github.com/saint/ghquick/internal/ai"
-	"github.com/saint/ghquick/internal/cache"
 	"github.com/saint/ghquick/internal/config"
 	"github.com/saint/ghquick/internal/git"
 	"github.com/saint/ghquick/internal/github"
@@ -20,48 +19,79 @@ var (
 	repoName   string
 	commitMsg  string
 	autoCommit bool
-	repoCache  *cache.RepoCache
+	// noAI builds the commit message from the diff without a model
+	noAI       bool
 	debug      bool
 	logger     *log.Logger
 	private    bool
-	timeout    time.Duration = 120 * time.Second
+	timeout    time.Duration = config.DefaultTimeout
+	pushBranch string
+	pushRemote string
+	// setVisibility allows changing the visibility of an existing repo
+	setVisibility bool
 )
 
 func init() {
 	rootCmd.AddCommand(pushCmd)
-	repoCache = cache.NewRepoCache()
 
 	pushCmd.Flags().StringVar(&repoName, "name", "", "Repository name (defaults to current directory name)")
 	pushCmd.Flags().StringVar(&commitMsg, "commitmsg", "", "Commit message")
+	pushCmd.Flags().BoolVar(&noAI, "no-ai", false, "Build the commit message from the changed files without calling the AI provider")
+	pushCmd.Flags().Int("candidates", 1, "Generate this many commit messages and choose one")
+	pushCmd.Flags().String("commit-format", "", "Generated message format: subject, or full for a body and footers")
+	pushCmd.Flags().StringArrayVar(&commitTrailers, "trailer", nil, "Add a trailer such as \"Co-authored-by: Name <email>\" (repeatable)")
 	pushCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
-	pushCmd.Flags().BoolVar(&private, "private", false, "Create repository as private")
-	pushCmd.Flags().DurationVar(&timeout, "timeout", timeout, "Timeout for operations (default 2m)")
+	pushCmd.Flags().BoolVar(&private, "private", false, "Create repository as private (shorthand for --visibility private)")
+	pushCmd.Flags().String("visibility", "", "Visibility for a new repository: public, private or internal")
+	pushCmd.Flags().BoolVar(&setVisibility, "set-visibility", false, "Also change the visibility of an existing repository")
+	pushCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Skip confirmation prompts and commit message review")
+	pushCmd.Flags().BoolVar(&stagedOnly, "staged-only", false, "Commit exactly what is already staged")
+	pushCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Pick the files to stage from a list")
+	pushCmd.Flags().BoolVar(&allowSecrets, "allow-secrets", false, "Commit even if the secret scanner finds something")
+	pushCmd.Flags().DurationVar(&timeout, "timeout", timeout, "Timeout for operations")
+	pushCmd.Flags().StringVar(&pushBranch, "branch", "", "Remote branch to push to (defaults to the upstream or current branch)")
+	pushCmd.Flags().StringVar(&pushRemote, "remote", "", "Remote to push to (defaults to the upstream remote or configured remote)")
+	pushCmd.Flags().BoolVar(&openPR, "pr", false, "Open a pull request after pushing")
+	pushCmd.Flags().BoolVar(&fixRemote, "fix-remote", false, "Repoint an existing remote that doesn't match --owner/--name")
+	addOwnerFlags(pushCmd.Flags())
+	addPullRequestFlags(pushCmd.Flags())
+}
+
+// pushCapabilities declares what this push invocation needs credentials for;
+// the AI key is only required when a message or PR text is generated
+func pushCapabilities() []config.Capability {
+	caps := []config.Capability{config.CapGitHub}
+	if autoCommit && !noAI {
+		caps = append(caps, config.CapAICommit)
+	}
+	if openPR && prTitle == "" {
+		caps = append(caps, config.CapAIPullRequest)
+	}
+	return caps
 }
 
 var pushCmd = &cobra.Command{
-	Use:   "push",
+	Use:   "push [start] [pathspec...]",
 	Short: "Push changes to GitHub",
 	Long: `Push changes to GitHub with optional AI-powered commit messages.
 Example: 
   ghquick push start        # AI-powered push with automatic commit message
+  ghquick push start src/   # only stage changes under src/
+  ghquick push --no-ai      # message built from the changed files, offline
+  ghquick push --staged-only --commitmsg "fix: typo"
   ghquick push --name my-repo --commitmsg "feature: new stuff"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logger = log.New(debug)
-		if len(args) > 0 && args[0] == "start" {
+
+		// "start" selects AI mode unless it comes after "--"; the rest are pathspecs
+		pathspecs := args
+		if len(args) > 0 && args[0] == "start" && cmd.ArgsLenAtDash() != 0 {
 			autoCommit = true
+			pathspecs = args[1:]
 		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
-
-		// Load configuration
-		logger.Step("Loading configuration...")
-		cfg, err := config.LoadFromEnv()
-		if err != nil {
-			logger.Error("Failed to load configuration")
-			return fmt.Errorf("failed to load config: %w", err)
+		if noAI && commitMsg == "" {
+			autoCommit = true
 		}
-		logger.Success("Configuration loaded")
 
 		// Get current working directory
 		wd, err := os.Getwd()
@@ -70,30 +100,92 @@ Example:
 			return fmt.Errorf("failed to get working directory: %w", err)
 		}
 
-		// If repo name is not provided, use current directory name
-		if repoName == "" {
-			repoName = filepath.Base(wd)
-			logger.Info("Using current directory name as repository name: %s", repoName)
+		// Load configuration
+		logger.Step("Loading configuration...")
+		cfg, err := loadConfig(cmd, wd)
+		if err == nil {
+			err = cfg.Require(pushCapabilities()...)
+		}
+		if err != nil {
+			logger.Error("Failed to load configuration")
+			return fmt.Errorf("failed to load config: %w", err)
 		}
+		logger.Success("Configuration loaded")
+		timeout = cfg.Timeout
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
 
 		// Initialize services
 		gitOps := git.NewOperations(wd, debug)
-		ghClient := github.NewClient(cfg.GitHubToken, debug)
-		commitGen := ai.NewCommitMessageGenerator(cfg.OpenAIKey)
+		setupRemote := targetRemote(ctx, cfg, gitOps)
+		remoteURL, err := resolveRepo(ctx, cfg, gitOps, setupRemote, wd, "pass --fix-remote")
+		if err != nil {
+			return err
+		}
+		ghClient, err := connectGitHub(cmd, cfg, gitOps, remoteURL)
+		if err != nil {
+			return err
+		}
+		if ghClient == nil && openPR {
+			return fmt.Errorf("cannot open a pull request on %s: set github_host to use it with ghquick", remoteURL.Host)
+		}
 
 		// Ensure GitHub repository exists
-		if err := ghClient.EnsureRepositoryExists(ctx, repoName, private); err != nil {
-			return fmt.Errorf("failed to ensure repository exists: %w", err)
+		repo := &github.Repo{Owner: remoteURL.Owner, Name: remoteURL.Repo}
+		if ghClient != nil {
+			repo, err = ghClient.EnsureRepositoryExists(ctx, remoteURL.Owner, remoteURL.Repo, cfg.Visibility)
+			if err != nil {
+				return fmt.Errorf("failed to ensure repository exists: %w", err)
+			}
+			if err := reconcileVisibility(ctx, cfg, ghClient, repo); err != nil {
+				return err
+			}
+		}
+
+		// A fresh local repository starts on GitHub's default branch
+		initialBranch := repo.DefaultBranch
+		if initialBranch == "" {
+			initialBranch = cfg.DefaultBranch
 		}
 
 		// Ensure git is set up
-		if err := gitOps.EnsureGitSetup(ctx, repoName); err != nil {
+		if err := gitOps.EnsureGitSetup(ctx, initialBranch); err != nil {
+			return fmt.Errorf("failed to setup git: %w", err)
+		}
+		if err := gitOps.EnsureRemote(ctx, setupRemote, remoteURL, fixRemote); err != nil {
 			return fmt.Errorf("failed to setup git: %w", err)
 		}
 
-		// Stage all files first
-		if err := gitOps.StageAll(ctx); err != nil {
-			if err.Error() == "no changes to commit" {
+		// Push to the remote that was set up above
+		target, err := gitOps.ResolvePushTarget(ctx, setupRemote, pushBranch, cfg.Remote)
+		if err != nil {
+			return fmt.Errorf("failed to determine push target: %w", err)
+		}
+		// A pull request into the branch being pushed is refused before
+		// anything is committed
+		var prBaseBranch string
+		if openPR {
+			if prBaseBranch, err = pullRequestBase(cfg, repo, target); err != nil {
+				return err
+			}
+		}
+
+		// Only fill in identity that git doesn't already have
+		override := git.Identity{Name: cfg.UserName, Email: cfg.UserEmail}
+		if err := gitOps.EnsureIdentity(ctx, override, func(ctx context.Context) (git.Identity, error) {
+			if ghClient == nil {
+				return git.Identity{}, fmt.Errorf("set user.name and user.email in git, or user_name and user_email in ghquick's config")
+			}
+			name, email, err := ghClient.AuthenticatedIdentity(ctx)
+			return git.Identity{Name: name, Email: email}, err
+		}); err != nil {
+			return fmt.Errorf("failed to configure git identity: %w", err)
+		}
+
+		// Stage the selected changes
+		if err := stageChanges(ctx, cfg, gitOps, pathspecs); err != nil {
+			if errors.Is(err, git.ErrNoChanges) {
 				logger.Warning("No changes to commit")
 				return nil
 			}
@@ -106,22 +198,30 @@ Example:
 			return fmt.Errorf("failed to get diff: %w", err)
 		}
 
+		// Nothing leaves the machine, to GitHub or the AI provider, until the
+		// staged changes have been checked for credentials
+		if err := checkSecrets(ctx, cfg, gitOps, diff); err != nil {
+			return err
+		}
+
 		// Generate commit message if needed
 		if autoCommit {
-			logger.Step("Generating commit message...")
-			result := make(chan ai.GenerateResult, 1)
-			commitGen.GenerateFromDiffAsync(ctx, diff, result)
-
-			select {
-			case res := <-result:
-				if res.Error != nil {
-					logger.Error("Failed to generate commit message")
-					return fmt.Errorf("failed to generate commit message: %w", res.Error)
+			commitMsg, err = generateCommitMessage(cfg, gitOps, diff)
+			if err != nil {
+				if errors.Is(err, errCommitAborted) {
+					logger.Warning("Commit aborted, changes are left staged")
+					return nil
 				}
-				commitMsg = res.Message
-				logger.Success("Commit message generated: %s", commitMsg)
-			case <-ctx.Done():
-				return ctx.Err()
+				return fmt.Errorf("failed to generate commit message: %w", err)
+			}
+
+			// Time spent reviewing the message doesn't count against the timeout
+			cancel()
+			ctx, cancel = context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+		} else if commitMsg != "" {
+			if commitMsg, err = withTrailers(commitMsg); err != nil {
+				return err
 			}
 		}
 
@@ -135,30 +235,117 @@ Example:
 			return fmt.Errorf("failed to commit: %w", err)
 		}
 
-		// Push changes with retry
-		maxRetries := 3
-		for i := 0; i < maxRetries; i++ {
-			if i > 0 {
-				logger.Warning("Retrying push (attempt %d/%d)...", i+1, maxRetries)
-				time.Sleep(2 * time.Second) // Wait before retry
-			}
-
-			err := gitOps.Push(ctx, "origin", "main")
-			if err == nil {
-				logger.Success("🚀 Successfully pushed changes to GitHub!")
-				return nil
+		if err := pushWithRetry(ctx, gitOps, target); err != nil {
+			// The repository may have been deleted or moved since it was cached
+			if ghClient != nil {
+				ghClient.ForgetRepo(remoteURL.Owner, remoteURL.Repo)
 			}
+			return err
+		}
 
-			if ctx.Err() != nil {
-				logger.Error("Operation timed out")
-				return fmt.Errorf("operation timed out after %v: %w", timeout, ctx.Err())
-			}
+		if openPR {
+			return openPullRequest(ctx, cfg, gitOps, ghClient, repo, target, prBaseBranch)
+		}
+		return nil
+	},
+}
 
-			if i == maxRetries-1 {
-				return fmt.Errorf("failed to push after %d attempts: %w", maxRetries, err)
-			}
+// targetRemote returns the remote this push goes to, the same way
+// ResolvePushTarget picks it: --remote, then the current branch's
+// upstream, then the configured remote. The repository, credentials and
+// API client are all set up for this remote.
+func targetRemote(ctx context.Context, cfg *config.Config, gitOps *git.Operations) string {
+	if pushRemote != "" {
+		return pushRemote
+	}
+	// A fresh or detached repository has no upstream to follow
+	if branch, err := gitOps.CurrentBranch(ctx); err == nil {
+		if remote, _ := gitOps.Upstream(ctx, branch); remote != "" {
+			return remote
 		}
+	}
+	return cfg.Remote
+}
 
+// reconcileVisibility applies the requested visibility to an existing
+// repository, only with --set-visibility and after confirmation
+func reconcileVisibility(ctx context.Context, cfg *config.Config, ghClient *github.Client, repo *github.Repo) error {
+	want := cfg.Visibility
+	if repo.Created || want == "" || want == repo.Visibility {
 		return nil
-	},
+	}
+	if !setVisibility {
+		logger.Info("Repository is %s; leaving it as is (pass --set-visibility to make it %s)", repo.Visibility, want)
+		return nil
+	}
+
+	ok, err := confirm(fmt.Sprintf("Change %s/%s from %s to %s?", repo.Owner, repo.Name, repo.Visibility, want))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		logger.Warning("Keeping repository %s", repo.Visibility)
+		return nil
+	}
+	if err := ghClient.SetVisibility(ctx, repo.Owner, repo.Name, want); err != nil {
+		return err
+	}
+	repo.Visibility = want
+	return nil
+}
+
+const (
+	// pushAttempts bounds how often a push failing for a transient
+	// reason is tried
+	pushAttempts = 4
+	// pushBackoff is the delay before the first retry, doubling after
+	// each one up to maxPushBackoff
+	pushBackoff    = time.Second
+	maxPushBackoff = 15 * time.Second
+)
+
+// pushWithRetry pushes target, retrying with backoff only when the
+// failure looks transient, such as a dropped connection. Other failures
+// are returned straight away with advice on fixing them.
+func pushWithRetry(ctx context.Context, gitOps *git.Operations, target *git.PushTarget) error {
+	for attempt := 1; ; attempt++ {
+		err := gitOps.Push(ctx, target)
+		if err == nil {
+			logger.Success("🚀 Successfully pushed changes to GitHub!")
+			return nil
+		}
+		if ctx.Err() != nil {
+			logger.Error("Operation timed out")
+			return fmt.Errorf("operation timed out after %v: %w", timeout, ctx.Err())
+		}
+
+		var pushErr *git.PushError
+		if !errors.As(err, &pushErr) || !pushErr.Transient() {
+			return err
+		}
+		if attempt == pushAttempts {
+			return fmt.Errorf("gave up after %d attempts: %w", pushAttempts, err)
+		}
+
+		delay := backoff(attempt)
+		logger.Warning("Push failed (%s), retrying in %s (attempt %d/%d)...",
+			pushErr.Kind, delay.Round(100*time.Millisecond), attempt+1, pushAttempts)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			logger.Error("Operation timed out")
+			return fmt.Errorf("operation timed out after %v: %w", timeout, ctx.Err())
+		}
+	}
+}
+
+// backoff returns the delay before retry number attempt: exponential,
+// capped, with jitter so concurrent runs don't retry in lockstep
+func backoff(attempt int) time.Duration {
+	d := pushBackoff << (attempt - 1)
+	if d <= 0 || d > maxPushBackoff {
+		d = maxPushBackoff
+	}
+	// Anywhere from half to the full delay
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
 }
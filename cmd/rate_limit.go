@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/saint/ghquick/internal/config"
+	"github.com/saint/ghquick/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(rateLimitCmd)
+
+	rateLimitCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
+}
+
+var rateLimitCmd = &cobra.Command{
+	Use:   "rate-limit",
+	Short: "Show the remaining GitHub API quota",
+	Long: `Show how many GitHub API requests the configured token has left on
+github_host for the core, search and GraphQL APIs, and when each quota
+resets. Checking doesn't use up any quota.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger = log.New(debug)
+
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		cfg, err := loadConfig(cmd, wd)
+		if err == nil {
+			err = cfg.Require(config.CapGitHubAPI)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		ghClient, err := newGitHubClient(cfg, cfg.GitHubHost)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		limits, err := ghClient.RateLimits(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RESOURCE\tREMAINING\tLIMIT\tRESETS")
+		for _, l := range limits {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s (in %s)\n", l.Resource, l.Remaining, l.Limit,
+				l.Reset.Format("15:04"), time.Until(l.Reset).Round(time.Second))
+		}
+		return w.Flush()
+	},
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/saint/ghquick/internal/cache"
+	"github.com/saint/ghquick/internal/config"
+	"github.com/saint/ghquick/internal/git"
+	"github.com/saint/ghquick/internal/github"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	repoOwner string
+	fixRemote bool
+)
+
+// addOwnerFlags registers --owner and its --org alias
+func addOwnerFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&repoOwner, "owner", "", "User or organization that owns the repository")
+	fs.StringVar(&repoOwner, "org", "", "Alias for --owner")
+}
+
+// resolveRepo works out which GitHub repository the command targets.
+//
+// An existing remote is authoritative: its host, owner and name are used
+// and --owner/--name only take effect with --fix-remote, otherwise a
+// mismatch is reported. Without a remote the repository is --owner (or the
+// configured owner, or the user) / --name (or the directory name) on the
+// configured host. fixHint tells the user how to repoint the remote from
+// the calling command, since only push can.
+func resolveRepo(ctx context.Context, cfg *config.Config, gitOps *git.Operations, remote, wd, fixHint string) (*git.RemoteURL, error) {
+	want := &git.RemoteURL{Host: cfg.GitHubHost, Owner: repoOwner, Repo: repoName}
+
+	current, err := gitOps.RemoteInfo(ctx, remote)
+	if err != nil {
+		if !fixRemote {
+			return nil, fmt.Errorf("remote %s: %w; %s to point it at GitHub", remote, err, fixHint)
+		}
+		logger.Warning("Remote %s is not a GitHub URL, it will be replaced", remote)
+	}
+
+	if current != nil {
+		// Keep the remote's host and transport (SSH stays SSH)
+		want.Host, want.SSH, want.User, want.Port = current.Host, current.SSH, current.User, current.Port
+		if want.Owner == "" {
+			want.Owner = current.Owner
+		}
+		if want.Repo == "" {
+			want.Repo = current.Repo
+		}
+		if !want.Same(current) && !fixRemote {
+			logger.Warning("Remote %s points at %s, not %s; using the remote (%s to repoint it)", remote, current, want, fixHint)
+			return current, nil
+		}
+		return want, nil
+	}
+
+	if want.Owner == "" {
+		want.Owner = cfg.Owner
+	}
+	if want.Owner == "" {
+		want.Owner = cfg.GitHubUsername
+	}
+	if want.Repo == "" {
+		want.Repo = filepath.Base(wd)
+		logger.Info("Using current directory name as repository name: %s", want.Repo)
+	}
+	return want, nil
+}
+
+// connectGitHub routes git's authentication through the configured token
+// and returns an API client for remote's host, but only when that host is
+// the configured github_host. For any other host it returns nil: git then
+// uses your own credentials and GitHub API calls are skipped, so the token
+// never reaches a server it wasn't issued for.
+func connectGitHub(cmd *cobra.Command, cfg *config.Config, gitOps *git.Operations, remote *git.RemoteURL) (*github.Client, error) {
+	if !cfg.TrustsHost(remote.Host) {
+		logger.Warning("%s is not the configured github_host (%s); using your own git credentials and skipping GitHub API calls",
+			remote.Host, cfg.GitHubHost)
+		return nil, nil
+	}
+	helper, err := credentialHelper(cmd)
+	if err != nil {
+		return nil, err
+	}
+	gitOps.SetCredentialHelper(helper)
+	return newGitHubClient(cfg, remote.Host)
+}
+
+// newGitHubClient creates the API client for host, remembering repository
+// lookups and API responses in the on-disk cache unless cache_ttl is zero
+func newGitHubClient(cfg *config.Config, host string) (*github.Client, error) {
+	client, err := github.NewClient(cfg.GitHubToken, host, debug)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CacheTTL > 0 {
+		repos, err := cache.OpenRepoStore(cfg.CacheTTL)
+		if err != nil {
+			logger.Debug("Repository cache disabled: %v", err)
+			return client, nil
+		}
+		client.SetRepoStore(repos)
+		responses, err := cache.OpenStore(cache.ResponseStoreFile)
+		if err != nil {
+			logger.Debug("Response cache disabled: %v", err)
+			return client, nil
+		}
+		client.SetResponseStore(responses)
+	}
+	return client, nil
+}
+
+// repoFile resolves the file named by key, or fallback when it is unset,
+// against the repository root. A file the repository picked, through its
+// .ghquick.yaml or the default location, must stay inside the repository
+// once symlinks are followed. A missing file is returned as is.
+func repoFile(ctx context.Context, cfg *config.Config, gitOps *git.Operations, key, fallback string) (string, error) {
+	path := cfg.Get(key)
+	fromRepo := path == "" || cfg.Sources[key] == config.SourceRepo
+	if path == "" {
+		path = fallback
+	}
+	if filepath.IsAbs(path) && !fromRepo {
+		return path, nil
+	}
+
+	root, err := gitOps.TopLevel(ctx)
+	if err != nil {
+		return "", err
+	}
+	full := filepath.Join(root, path)
+	if !fromRepo {
+		return full, nil
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve repository root: %w", err)
+	}
+	real, err := filepath.EvalSymlinks(full)
+	if errors.Is(err, fs.ErrNotExist) {
+		return full, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", key, err)
+	}
+	if rel, err := filepath.Rel(realRoot, real); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s %s points outside the repository", key, path)
+	}
+	return full, nil
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saint/ghquick/internal/ai"
+	"github.com/saint/ghquick/internal/config"
+	"github.com/saint/ghquick/internal/git"
+)
+
+// errCommitAborted is returned when the user aborts at message review
+var errCommitAborted = errors.New("commit aborted")
+
+// generateCommitMessage asks the model for a message and, on a terminal,
+// lets the user accept, edit, regenerate or abort it. With ai_candidates
+// above one the user first picks among several messages, and the pick is
+// recorded. --yes and non-interactive runs take the first message as is.
+//
+// Each generation gets its own timeout so time spent reading or editing
+// doesn't eat into it.
+func generateCommitMessage(cfg *config.Config, gitOps *git.Operations, diff string) (string, error) {
+	optsCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	opts, err := commitOptions(optsCtx, cfg, gitOps)
+	cancel()
+	if err != nil {
+		return "", err
+	}
+
+	var commitGen *ai.CommitMessageGenerator
+	if !noAI {
+		if commitGen, err = newCommitGenerator(cfg, opts); err != nil {
+			return "", err
+		}
+	}
+
+	generate := func(hint string) ([]string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+
+		var candidates []string
+		var err error
+		switch {
+		case noAI:
+			logger.Step("Building commit message from the changed files...")
+			candidates = []string{ai.HeuristicMessage(diff, opts)}
+		default:
+			if cfg.AICandidates > 1 {
+				logger.Step("Generating %d candidate commit messages...", cfg.AICandidates)
+			} else {
+				logger.Step("Generating commit message...")
+			}
+			candidates, err = commitGen.GenerateCandidates(ctx, diff, hint, cfg.AICandidates)
+			if err != nil {
+				// Don't lose the push to a provider outage
+				logger.Warning("AI generation failed: %v", err)
+				logger.Warning("Falling back to a message built from the changed files")
+				candidates = []string{ai.HeuristicMessage(diff, opts)}
+			}
+		}
+		for i := range candidates {
+			if candidates[i], err = withTrailers(candidates[i]); err != nil {
+				return nil, err
+			}
+		}
+		return candidates, nil
+	}
+
+	candidates, err := generate("")
+	if err != nil {
+		return "", err
+	}
+	if assumeYes || !isInteractive() {
+		logger.Success("Commit message generated: %s", candidates[0])
+		return candidates[0], nil
+	}
+
+	chosen, err := chooseCandidate(candidates)
+	if err != nil {
+		return "", err
+	}
+	message := candidates[chosen]
+	edited := false
+
+	regenerate := func(hint string) error {
+		next, err := generate(hint)
+		if err != nil {
+			logger.Warning("%v", err)
+			return nil
+		}
+		if chosen, err = chooseCandidate(next); err != nil {
+			return err
+		}
+		candidates, message, edited = next, next[chosen], false
+		return nil
+	}
+
+	for {
+		fmt.Printf("\nCommit message:\n\n%s\n\n", indent(message))
+		answer, err := readLine("[a]ccept, [e]dit, [r]egenerate, regenerate with [h]int, a[b]ort (default accept): ")
+		if err != nil {
+			return "", err
+		}
+
+		switch strings.ToLower(answer) {
+		case "", "a", "accept":
+			if len(candidates) > 1 {
+				recordChoice(gitOps, ai.Choice{
+					Candidates: candidates,
+					Chosen:     chosen,
+					Final:      message,
+					Edited:     edited,
+				})
+			}
+			return message, nil
+		case "e", "edit":
+			next, err := gitOps.EditMessage(context.Background(), message)
+			if err != nil {
+				logger.Warning("%v", err)
+				continue
+			}
+			if next == "" {
+				return "", errCommitAborted
+			}
+			edited = edited || next != message
+			message = next
+		case "r", "regenerate":
+			if err := regenerate(""); err != nil {
+				return "", err
+			}
+		case "h", "hint":
+			hint, err := readLine("Hint for the model: ")
+			if err != nil {
+				return "", err
+			}
+			if err := regenerate(hint); err != nil {
+				return "", err
+			}
+		case "b", "abort", "q", "quit":
+			return "", errCommitAborted
+		default:
+			logger.Warning("Unknown choice %q", answer)
+		}
+	}
+}
+
+// chooseCandidate lets the user pick one of several messages
+func chooseCandidate(candidates []string) (int, error) {
+	if len(candidates) == 1 {
+		return 0, nil
+	}
+
+	fmt.Println()
+	for i, c := range candidates {
+		fmt.Printf("%2d) %s\n\n", i+1, strings.TrimLeft(indent(c), " "))
+	}
+	for {
+		answer, err := readLine(fmt.Sprintf("Choose a message [1-%d], or a[b]ort (default 1): ", len(candidates)))
+		if err != nil {
+			return 0, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return 0, nil
+		case "b", "abort", "q", "quit":
+			return 0, errCommitAborted
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(candidates) {
+			return n - 1, nil
+		}
+		logger.Warning("Enter a number from 1 to %d", len(candidates))
+	}
+}
+
+// recordChoice appends the user's pick to the choices file; failing to
+// record never blocks the commit
+func recordChoice(gitOps *git.Operations, choice ai.Choice) {
+	path, err := ai.ChoicesFile()
+	if err != nil {
+		logger.Debug("Not recording choice: %v", err)
+		return
+	}
+	choice.Time = time.Now().UTC()
+	if root, err := gitOps.TopLevel(context.Background()); err == nil {
+		choice.Repo = root
+	}
+	if err := ai.RecordChoice(path, choice); err != nil {
+		logger.Debug("Not recording choice: %v", err)
+	}
+}
+
+// indent prefixes every line of a message for display
+func indent(message string) string {
+	return "    " + strings.ReplaceAll(message, "\n", "\n    ")
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/saint/ghquick/internal/config"
+	"github.com/saint/ghquick/internal/git"
+)
+
+var (
+	stagedOnly   bool
+	interactive  bool
+	allowSecrets bool
+)
+
+// stageChanges prepares the index for the commit according to the flags:
+// exactly what the user staged, a picked subset, the given pathspecs, or
+// everything. Configured ignore globs apply to all but --staged-only.
+func stageChanges(ctx context.Context, cfg *config.Config, gitOps *git.Operations, pathspecs []string) error {
+	switch {
+	case stagedOnly:
+		if len(pathspecs) > 0 || interactive {
+			return fmt.Errorf("--staged-only can't be combined with paths or --interactive")
+		}
+		logger.Step("Using staged changes only...")
+		return gitOps.RequireStaged(ctx)
+	case interactive:
+		if len(pathspecs) > 0 {
+			return fmt.Errorf("--interactive can't be combined with paths")
+		}
+		picked, err := pickFiles(ctx, cfg, gitOps)
+		if err != nil {
+			return err
+		}
+		pathspecs = picked
+	}
+
+	return gitOps.Stage(ctx, git.StageOptions{Pathspecs: pathspecs, Exclude: cfg.Ignore})
+}
+
+// checkSecrets blocks the commit when the staged diff looks like it
+// contains credentials, unless --allow-secrets is given
+func checkSecrets(ctx context.Context, cfg *config.Config, gitOps *git.Operations, diff string) error {
+	allowFile, err := repoFile(ctx, cfg, gitOps, config.KeySecretsAllow, git.DefaultAllowlistFile)
+	if err != nil {
+		return err
+	}
+	allow, err := git.LoadSecretAllowlist(allowFile)
+	if err != nil {
+		return err
+	}
+
+	logger.Step("Scanning staged changes for secrets...")
+	findings := git.ScanDiffForSecrets(diff, allow)
+	if len(findings) == 0 {
+		logger.Success("No secrets found")
+		return nil
+	}
+
+	for _, f := range findings {
+		logger.Warning("Possible secret: %s", f)
+	}
+	if allowSecrets {
+		logger.Warning("Continuing because --allow-secrets was given")
+		return nil
+	}
+	logger.Error("Refusing to commit possible secrets")
+	return fmt.Errorf("%w\nunstage the files, mark the line with %q, add an entry to %s, or pass --allow-secrets",
+		&git.SecretsError{Findings: findings}, git.AllowSecretMarker, allowFile)
+}
+
+// pickFiles lists changed files and lets the user choose which to stage
+func pickFiles(ctx context.Context, cfg *config.Config, gitOps *git.Operations) ([]string, error) {
+	entries, err := gitOps.Status(ctx, cfg.Ignore)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, git.ErrNoChanges
+	}
+
+	fmt.Println("Changed files:")
+	for i, entry := range entries {
+		fmt.Printf("  %3d) %s\n", i+1, entry)
+	}
+
+	for {
+		answer, err := readLine("Files to stage (e.g. 1 3-5, a for all, empty to abort): ")
+		if err != nil {
+			return nil, fmt.Errorf("file picker: %w", err)
+		}
+		if answer == "" {
+			return nil, fmt.Errorf("aborted: no files selected")
+		}
+
+		indexes, err := parseSelection(answer, len(entries))
+		if err != nil {
+			logger.Warning("%v", err)
+			continue
+		}
+
+		// Porcelain paths are relative to the repository root
+		var pathspecs []string
+		for _, i := range indexes {
+			pathspecs = append(pathspecs, ":(top,literal)"+entries[i].Path)
+			if entries[i].OrigPath != "" {
+				pathspecs = append(pathspecs, ":(top,literal)"+entries[i].OrigPath)
+			}
+		}
+		return pathspecs, nil
+	}
+}
+
+// parseSelection turns "1 3-5,7" (or "a") into zero-based indexes below n
+func parseSelection(answer string, n int) ([]int, error) {
+	if answer == "a" || answer == "all" {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	seen := make(map[int]bool)
+	var indexes []int
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ' ' || r == ',' }) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", field)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", field)
+			}
+		}
+		if start < 1 || end > n || start > end {
+			return nil, fmt.Errorf("selection %q is out of range 1-%d", field, n)
+		}
+		for i := start - 1; i < end; i++ {
+			if !seen[i] {
+				seen[i] = true
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return indexes, nil
+}
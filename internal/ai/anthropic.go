@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com/v1"
+	anthropicVersion = "2023-06-01"
+)
+
+// anthropicGenerator calls the Anthropic Messages API
+type anthropicGenerator struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+}
+
+func newAnthropicGenerator(cfg ProviderConfig) *anthropicGenerator {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = anthropicBaseURL
+	}
+	return &anthropicGenerator{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     cfg.APIKey,
+		model:      cfg.Model,
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float32            `json:"temperature"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (g *anthropicGenerator) Generate(ctx context.Context, req Request) (string, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:       g.model,
+		System:      req.System,
+		Messages:    []anthropicMessage{{Role: "user", Content: req.User}},
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	httpReq.Header.Set("x-api-key", g.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := g.httpClient.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var parsed anthropicResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return "", fmt.Errorf("unexpected response (status %d): %w", resp.StatusCode, err)
+	}
+	if parsed.Error != nil {
+		return "", fmt.Errorf("anthropic API error (status %d): %s: %s", resp.StatusCode, parsed.Error.Type, parsed.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("anthropic API returned status %d", resp.StatusCode)
+	}
+
+	var text strings.Builder
+	for _, block := range parsed.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("empty response from model %s", g.model)
+	}
+	return text.String(), nil
+}
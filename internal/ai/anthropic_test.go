@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicGenerate(t *testing.T) {
+	var got anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/messages" {
+			t.Errorf("request = %s %s, want POST /messages", r.Method, r.URL.Path)
+		}
+		for header, want := range map[string]string{
+			"x-api-key":         "ant-key",
+			"anthropic-version": anthropicVersion,
+			"content-type":      "application/json",
+		} {
+			if v := r.Header.Get(header); v != want {
+				t.Errorf("%s = %q, want %q", header, v, want)
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"fix: handle "},{"type":"tool_use"},{"type":"text","text":"empty diffs"}]}`))
+	}))
+	defer srv.Close()
+
+	gen, err := NewMessageGenerator(ProviderConfig{Provider: ProviderAnthropic, APIKey: "ant-key", BaseURL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := gen.Generate(context.Background(), Request{System: "be brief", User: "the diff", MaxTokens: 100, Temperature: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "fix: handle empty diffs" {
+		t.Errorf("text = %q", text)
+	}
+
+	if got.Model != DefaultAnthropicModel || got.System != "be brief" || got.MaxTokens != 100 || got.Temperature != 0.5 {
+		t.Errorf("request = %+v", got)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Role != "user" || got.Messages[0].Content != "the diff" {
+		t.Errorf("messages = %+v", got.Messages)
+	}
+}
+
+func TestAnthropicGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"api error", http.StatusUnauthorized, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, "authentication_error: invalid x-api-key"},
+		{"bad status", http.StatusBadGateway, `{}`, "status 502"},
+		{"not json", http.StatusBadGateway, `<html>bad gateway</html>`, "unexpected response (status 502)"},
+		{"empty content", http.StatusOK, `{"content":[]}`, "empty response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			gen, err := NewMessageGenerator(ProviderConfig{Provider: ProviderAnthropic, APIKey: "ant-key", BaseURL: srv.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = gen.Generate(context.Background(), Request{User: "the diff"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
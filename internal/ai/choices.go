@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Choice records which of several generated candidates the user picked,
+// so preferred phrasing can be learned from later
+type Choice struct {
+	Time       time.Time `json:"time"`
+	Repo       string    `json:"repo"`
+	Candidates []string  `json:"candidates"`
+	// Chosen is the index of the picked candidate
+	Chosen int `json:"chosen"`
+	// Final is the message committed, which differs from the candidate
+	// when the user edited it
+	Final  string `json:"final"`
+	Edited bool   `json:"edited"`
+}
+
+// ChoicesFile returns where choices are recorded:
+// $XDG_DATA_HOME/ghquick/choices.jsonl, defaulting to ~/.local/share
+func ChoicesFile() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find data directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "ghquick", "choices.jsonl"), nil
+}
+
+// RecordChoice appends c to the JSON lines file at path
+func RecordChoice(path string, c Choice) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record choice: %w", err)
+	}
+	return nil
+}
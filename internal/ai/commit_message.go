@@ -4,17 +4,42 @@ import (
 	"context"
 	"fmt"
 	"strings"
-
-	"github.com/sashabaranov/go-openai"
+	"sync"
+	"unicode/utf8"
 )
 
+// maxAttempts bounds how often the model is asked again after returning
+// a message that doesn't parse or validate
+const maxAttempts = 3
+
+// Options tunes what the generator sends to the model and expects back
+type Options struct {
+	Diff DiffOptions
+	// Format is FormatSubject (the default) or FormatFull
+	Format MessageFormat
+	// Rules restrict the Conventional Commits types and scopes
+	Rules Rules
+	// Style carries conventions learned from the repository's history
+	Style Style
+	// Template renders the prompt; nil uses the built-in one
+	Template *PromptTemplate
+}
+
 type CommitMessageGenerator struct {
-	client *openai.Client
+	gen  MessageGenerator
+	opts Options
+
+	// The prepared diff is reused when regenerating for the same changes,
+	// which matters in map-reduce mode
+	mu           sync.Mutex
+	preparedFrom string
+	prepared     string
 }
 
-func NewCommitMessageGenerator(apiKey string) *CommitMessageGenerator {
+func NewCommitMessageGenerator(gen MessageGenerator, opts Options) *CommitMessageGenerator {
 	return &CommitMessageGenerator{
-		client: openai.NewClient(apiKey),
+		gen:  gen,
+		opts: opts,
 	}
 }
 
@@ -34,35 +59,225 @@ func (g *CommitMessageGenerator) GenerateFromDiffAsync(ctx context.Context, diff
 }
 
 func (g *CommitMessageGenerator) GenerateFromDiff(ctx context.Context, diff string) (string, error) {
-	systemPrompt := `You are a commit message generator. Given a git diff, generate a concise, 
-descriptive commit message following conventional commits format. Focus on the main changes and their purpose.
-Format: <type>(<scope>): <description>
-Types: feat, fix, docs, style, refactor, test, chore
+	return g.GenerateWithHint(ctx, diff, "")
+}
+
+// GenerateWithHint generates a commit message, steering the model with a
+// free-form hint from the user such as "mention the API rename". Replies
+// are normalized and checked against the Conventional Commits rules; ones
+// that still don't pass are sent back to the model with the problem a
+// couple of times before giving up.
+func (g *CommitMessageGenerator) GenerateWithHint(ctx context.Context, diff, hint string) (string, error) {
+	req, scope, err := g.request(ctx, diff, hint)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	return g.generate(ctx, req, scope)
+}
+
+// candidateTemperature spreads candidates out; one message stays at the
+// more predictable default
+const candidateTemperature = 0.8
+
+// GenerateCandidates asks for n messages in parallel and returns the
+// distinct ones in the order they were requested. It fails only if every
+// request does.
+func (g *CommitMessageGenerator) GenerateCandidates(ctx context.Context, diff, hint string, n int) ([]string, error) {
+	if n <= 1 {
+		message, err := g.GenerateWithHint(ctx, diff, hint)
+		if err != nil {
+			return nil, err
+		}
+		return []string{message}, nil
+	}
+
+	req, scope, err := g.request(ctx, diff, hint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	req.Temperature = candidateTemperature
+
+	messages := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			messages[i], errs[i] = g.generate(ctx, req, scope)
+		}(i)
+	}
+	wg.Wait()
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for i, message := range messages {
+		if errs[i] != nil {
+			continue
+		}
+		key := strings.ToLower(strings.Join(strings.Fields(message), " "))
+		if !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, message)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errs[0]
+	}
+	return candidates, nil
+}
+
+// generate sends req, re-prompting with the validation error while the
+// reply is invalid
+func (g *CommitMessageGenerator) generate(ctx context.Context, first Request, scope string) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		req := first
+		if lastErr != nil {
+			req.User += fmt.Sprintf("\n\nYour previous reply was rejected: %v. Reply again with a corrected commit message.", lastErr)
+		}
+
+		resp, err := g.gen.Generate(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate commit message: %w", err)
+		}
+
+		message, err := g.parse(resp, scope)
+		if err == nil {
+			return message, nil
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("failed to generate commit message: model output was invalid after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// RenderPrompt returns the request GenerateWithHint would send first, for
+// previewing prompt templates
+func (g *CommitMessageGenerator) RenderPrompt(ctx context.Context, diff, hint string) (Request, error) {
+	req, _, err := g.request(ctx, diff, hint)
+	return req, err
+}
+
+// request builds the prompt for diff from the template and returns it
+// with the scope inferred from the changed paths
+func (g *CommitMessageGenerator) request(ctx context.Context, diff, hint string) (Request, string, error) {
+	systemPrompt, maxTokens := g.systemPrompt()
+
+	prompt, err := g.diffForPrompt(ctx, diff)
+	if err != nil {
+		return Request{}, "", err
+	}
+
+	files := ParseDiff(diff)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	scope := ""
+	if !g.opts.Style.FreeForm {
+		scope = InferScope(paths, g.opts.Rules.Scopes)
+	}
+
+	system, user, err := g.opts.Template.render(PromptData{
+		Diff:         prompt,
+		Stat:         DiffStat(files),
+		Files:        paths,
+		Branch:       g.opts.Style.Branch,
+		RecentLog:    g.opts.Style.Examples,
+		Ticket:       g.opts.Style.Ticket,
+		Scope:        scope,
+		Hint:         hint,
+		Conventional: !g.opts.Style.FreeForm,
+		Types:        g.opts.Rules.types(),
+		Scopes:       g.opts.Rules.Scopes,
+	})
+	if err != nil {
+		return Request{}, "", err
+	}
+	if system != "" {
+		systemPrompt = system
+	}
+
+	return Request{
+		System:      systemPrompt,
+		User:        user,
+		MaxTokens:   maxTokens,
+		Temperature: 0.3,
+	}, scope, nil
+}
+
+// systemPrompt returns the instructions for the configured format and
+// style, and the reply length they need
+func (g *CommitMessageGenerator) systemPrompt() (string, int) {
+	format := "following conventional commits format"
+	if g.opts.Style.FreeForm {
+		format = "in the style of the repository's recent commits"
+	}
+
+	var prompt string
+	maxTokens := 60
+	if g.opts.Format == FormatFull {
+		prompt = `You are a commit message generator. Given a git diff, write a complete commit message
+` + format + `: a subject line, a blank line, a body, and optional footers.
+The subject line must be under 72 characters. The body explains what changed and why, in
+short paragraphs or a bullet list; skip it only for trivial changes.
+Add a "BREAKING CHANGE: <what breaks and how to migrate>" footer only when the change breaks
+compatibility, and a "Refs: <issue>" footer only for issues named in the diff or the guidance.
+Reply with the commit message only.`
+		maxTokens = 400
+	} else {
+		prompt = `You are a commit message generator. Given a git diff, generate a concise, 
+descriptive commit message ` + format + `. Focus on the main changes and their purpose.
 Keep it under 72 characters.`
+	}
 
-	resp, err := g.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: "gpt-4-1106-preview",
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: systemPrompt,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: fmt.Sprintf("Generate a commit message for this diff:\n\n%s", diff),
-				},
-			},
-			MaxTokens:   60,
-			Temperature: 0.3,
-		},
-	)
+	if !g.opts.Style.FreeForm {
+		prompt += "\nFormat: <type>(<scope>): <description>"
+		prompt += "\nTypes: " + strings.Join(g.opts.Rules.types(), ", ")
+		if scopes := g.opts.Rules.Scopes; len(scopes) > 0 {
+			prompt += "\nScopes (optional): " + strings.Join(scopes, ", ")
+		}
+	}
+	return prompt, maxTokens
+}
 
+// parse normalizes and validates a model reply and returns it formatted
+// for git. When the project restricts scopes and the reply has none, the
+// scope inferred from the changed paths is filled in.
+func (g *CommitMessageGenerator) parse(resp, scope string) (string, error) {
+	msg, err := ParseCommitMessage(Normalize(resp))
 	if err != nil {
-		return "", fmt.Errorf("failed to generate commit message: %w", err)
+		return "", err
+	}
+	if g.opts.Format != FormatFull {
+		msg = &CommitMessage{Subject: msg.Subject}
+	}
+
+	if !g.opts.Style.FreeForm {
+		subject, err := ParseSubject(msg.Subject)
+		if err != nil {
+			return "", err
+		}
+		rules := g.opts.Rules
+		rules.Repair(subject)
+		if err := rules.Check(subject); err != nil {
+			return "", err
+		}
+		if subject.Scope == "" && scope != "" && len(rules.Scopes) > 0 {
+			scoped := *subject
+			scoped.Scope = scope
+			if utf8.RuneCountInString(scoped.String()) <= MaxSubjectLength {
+				subject = &scoped
+			}
+		}
+		msg.Subject = subject.String()
 	}
 
-	message := strings.TrimSpace(resp.Choices[0].Message.Content)
-	return message, nil
+	if err := g.opts.Style.check(msg.Subject); err != nil {
+		return "", err
+	}
+	if err := msg.Validate(); err != nil {
+		return "", err
+	}
+	return msg.String(), nil
 }
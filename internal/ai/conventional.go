@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTypes are the Conventional Commits types allowed when none are
+// configured, following the Angular convention
+var DefaultTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// Subject is a parsed Conventional Commits subject line,
+// "type(scope)!: description"
+type Subject struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+var subjectPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?:\s*(.*)$`)
+
+// ParseSubject parses a Conventional Commits subject line
+func ParseSubject(line string) (*Subject, error) {
+	match := subjectPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return nil, fmt.Errorf("subject %q is not in the form type(scope): description", line)
+	}
+	s := &Subject{
+		Type:        match[1],
+		Scope:       strings.TrimSpace(match[2]),
+		Breaking:    match[3] == "!",
+		Description: strings.TrimSpace(match[4]),
+	}
+	if s.Description == "" {
+		return nil, fmt.Errorf("subject %q has no description", line)
+	}
+	if (match[2] != "" && s.Scope == "") || strings.ContainsAny(s.Scope, " \t") {
+		return nil, fmt.Errorf("scope %q must be a single word", match[2])
+	}
+	return s, nil
+}
+
+func (s *Subject) String() string {
+	var b strings.Builder
+	b.WriteString(s.Type)
+	if s.Scope != "" {
+		b.WriteString("(" + s.Scope + ")")
+	}
+	if s.Breaking {
+		b.WriteString("!")
+	}
+	b.WriteString(": " + s.Description)
+	return b.String()
+}
+
+// Rules are the project's Conventional Commits constraints
+type Rules struct {
+	// Types allowed in the subject; empty means DefaultTypes
+	Types []string
+	// Scopes allowed in the subject; empty allows any scope
+	Scopes []string
+}
+
+func (r Rules) types() []string {
+	if len(r.Types) == 0 {
+		return DefaultTypes
+	}
+	return r.Types
+}
+
+// Check validates s against the rules
+func (r Rules) Check(s *Subject) error {
+	if !contains(r.types(), s.Type) {
+		return fmt.Errorf("type %q is not allowed (use one of %s)", s.Type, strings.Join(r.types(), ", "))
+	}
+	if s.Scope != "" && len(r.Scopes) > 0 && !contains(r.Scopes, s.Scope) {
+		return fmt.Errorf("scope %q is not allowed (use one of %s, or none)", s.Scope, strings.Join(r.Scopes, ", "))
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Repair fixes cosmetic problems models commonly introduce: a capitalized
+// type, a capitalized description, and a trailing period
+func (r Rules) Repair(s *Subject) {
+	for _, t := range r.types() {
+		if strings.EqualFold(t, s.Type) {
+			s.Type = t
+		}
+	}
+	for _, scope := range r.Scopes {
+		if strings.EqualFold(scope, s.Scope) {
+			s.Scope = scope
+		}
+	}
+	s.Description = strings.TrimRight(s.Description, ". ")
+	if first, size := utf8.DecodeRuneInString(s.Description); unicode.IsUpper(first) {
+		// Leave acronyms like "API" alone
+		if next, _ := utf8.DecodeRuneInString(s.Description[size:]); !unicode.IsUpper(next) {
+			s.Description = string(unicode.ToLower(first)) + s.Description[size:]
+		}
+	}
+}
+
+var (
+	fencePattern  = regexp.MustCompile("(?s)^```[\\w-]*\\n(.*?)\\n?```$")
+	prefixPattern = regexp.MustCompile(`(?i)^(suggested |proposed |git )?(commit message|commit msg|message|subject)\s*:\s*`)
+)
+
+// quotePairs are the wrappers models put around a whole reply or subject
+var quotePairs = [][2]string{{"`", "`"}, {`"`, `"`}, {"'", "'"}, {"“", "”"}, {"**", "**"}}
+
+// Normalize strips the decoration models add around commit messages: code
+// fences, "Commit message:" labels and quotes or backticks around the
+// whole reply or the subject line
+func Normalize(text string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if m := fencePattern.FindStringSubmatch(text); m != nil {
+		text = strings.TrimSpace(m[1])
+	}
+	text = strings.TrimSpace(prefixPattern.ReplaceAllString(text, ""))
+	text = unquote(text)
+
+	subject, rest, found := strings.Cut(text, "\n")
+	subject = unquote(strings.TrimSpace(prefixPattern.ReplaceAllString(subject, "")))
+	if !found {
+		return subject
+	}
+	return subject + "\n" + rest
+}
+
+func unquote(s string) string {
+	for changed := true; changed; {
+		changed = false
+		for _, q := range quotePairs {
+			if len(s) > len(q[0])+len(q[1]) && strings.HasPrefix(s, q[0]) && strings.HasSuffix(s, q[1]) {
+				s = strings.TrimSpace(s[len(q[0]) : len(s)-len(q[1])])
+				changed = true
+			}
+		}
+	}
+	return s
+}
+
+// genericDirs are path components that say nothing about what changed
+var genericDirs = map[string]bool{
+	"internal": true,
+	"pkg":      true,
+	"src":      true,
+	"lib":      true,
+	"app":      true,
+}
+
+// InferScope guesses a scope from the changed paths: the first meaningful
+// directory all of them share, such as "git" for internal/git/*.go. It
+// returns "" when the files are spread out or, if allowed is not empty,
+// when the guess is not one of the allowed scopes.
+func InferScope(paths []string, allowed []string) string {
+	scope := ""
+	for _, p := range paths {
+		s := pathScope(p)
+		if s == "" || (scope != "" && s != scope) {
+			return ""
+		}
+		scope = s
+	}
+	if scope == "" || (len(allowed) > 0 && !contains(allowed, scope)) {
+		return ""
+	}
+	return scope
+}
+
+func pathScope(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	for _, part := range strings.Split(dir, "/") {
+		if !genericDirs[part] {
+			return strings.ToLower(strings.TrimPrefix(part, "."))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,428 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/saint/ghquick/internal/git"
+)
+
+// DiffMode controls how a diff that exceeds the token budget is shrunk
+type DiffMode string
+
+const (
+	// DiffModeTruncate trims hunks, then falls back to a stat summary
+	// plus the largest hunks
+	DiffModeTruncate DiffMode = "truncate"
+	// DiffModeMapReduce summarizes each file with its own request and
+	// writes the message from the summaries
+	DiffModeMapReduce DiffMode = "map-reduce"
+)
+
+// DefaultMaxDiffTokens keeps the prompt well inside the smallest context
+// window of the supported default models
+const DefaultMaxDiffTokens = 6000
+
+// mapConcurrency bounds the parallel per-file summary requests
+const mapConcurrency = 4
+
+// DiffOptions sets the token budget for diffs sent to the model
+type DiffOptions struct {
+	// MaxTokens is the approximate budget for the diff; 0 means the default
+	MaxTokens int
+	Mode      DiffMode
+}
+
+func (o DiffOptions) budget() int {
+	if o.MaxTokens <= 0 {
+		return DefaultMaxDiffTokens
+	}
+	return o.MaxTokens
+}
+
+// EstimateTokens approximates the token count of s at four bytes a token,
+// close enough for English and code on the models we target
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// FileDiff is the part of a unified diff that touches one file
+type FileDiff struct {
+	Path string
+	// Header is everything before the first hunk (diff --git, index, ---/+++)
+	Header    string
+	Hunks     []string
+	Binary    bool
+	Added     int
+	Deleted   int
+	Generated bool
+	// Created and Removed mark new and deleted files; RenamedFrom is the
+	// old path of a rename
+	Created     bool
+	Removed     bool
+	RenamedFrom string
+}
+
+// Text reassembles the file's diff
+func (f *FileDiff) Text() string {
+	return f.Header + strings.Join(f.Hunks, "")
+}
+
+// stub replaces a file's content with a one-line note
+func (f *FileDiff) stub() string {
+	if f.Binary {
+		return fmt.Sprintf("diff --git a/%s b/%s\n(binary file changed, content omitted)\n", f.Path, f.Path)
+	}
+	kind := "generated file"
+	if git.IsLockfile(f.Path) {
+		kind = "lockfile"
+	}
+	return fmt.Sprintf("diff --git a/%s b/%s\n(%s changed, +%d -%d lines, content omitted)\n", f.Path, f.Path, kind, f.Added, f.Deleted)
+}
+
+// generatedPatterns match paths of machine-written files
+var generatedPatterns = []string{
+	"*.min.js", "*.min.css", "*.map",
+	"*.pb.go", "*_gen.go", "*.gen.go", "*_generated.go", "*.generated.*",
+	"*.snap",
+}
+
+// generatedDirs are path components whose contents are vendored or built
+var generatedDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"dist":         true,
+}
+
+func isGenerated(p string) bool {
+	base := path.Base(p)
+	if git.IsLockfile(base) {
+		return true
+	}
+	for _, pattern := range generatedPatterns {
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	for _, dir := range strings.Split(path.Dir(p), "/") {
+		if generatedDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDiff splits a unified git diff into per-file pieces
+func ParseDiff(diff string) []*FileDiff {
+	var files []*FileDiff
+	var cur *FileDiff
+	var hunk *strings.Builder
+
+	flushHunk := func() {
+		if cur != nil && hunk != nil {
+			cur.Hunks = append(cur.Hunks, hunk.String())
+		}
+		hunk = nil
+	}
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushHunk()
+			cur = &FileDiff{Path: pathFromHeader(line)}
+			files = append(files, cur)
+			cur.Header += line
+			continue
+		case cur == nil:
+			continue
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			hunk = &strings.Builder{}
+		}
+
+		if hunk == nil {
+			cur.Header += line
+			switch {
+			case strings.HasPrefix(line, "+++ b/"):
+				cur.Path = strings.TrimSpace(strings.TrimPrefix(line, "+++ b/"))
+			case strings.HasPrefix(line, "Binary files "), strings.HasPrefix(line, "GIT binary patch"):
+				cur.Binary = true
+			case strings.HasPrefix(line, "new file mode"):
+				cur.Created = true
+			case strings.HasPrefix(line, "deleted file mode"):
+				cur.Removed = true
+			case strings.HasPrefix(line, "rename from "):
+				cur.RenamedFrom = strings.TrimSpace(strings.TrimPrefix(line, "rename from "))
+			}
+			continue
+		}
+
+		hunk.WriteString(line)
+		switch {
+		case strings.HasPrefix(line, "+"):
+			cur.Added++
+			if strings.Contains(line, "Code generated") && strings.Contains(line, "DO NOT EDIT") {
+				cur.Generated = true
+			}
+		case strings.HasPrefix(line, "-"):
+			cur.Deleted++
+		}
+	}
+	flushHunk()
+
+	for _, f := range files {
+		if isGenerated(f.Path) {
+			f.Generated = true
+		}
+	}
+	return files
+}
+
+// pathFromHeader takes the b/ path from a "diff --git a/x b/x" line; +++
+// lines override it when present since they handle spaces unambiguously
+func pathFromHeader(line string) string {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "diff --git "))
+	if i := strings.LastIndex(line, " b/"); i >= 0 {
+		return line[i+3:]
+	}
+	return line
+}
+
+// DiffStat renders a summary like git diff --stat for files
+func DiffStat(files []*FileDiff) string {
+	width := 0
+	for _, f := range files {
+		if len(f.Path) > width {
+			width = len(f.Path)
+		}
+	}
+
+	var b strings.Builder
+	added, deleted := 0, 0
+	for _, f := range files {
+		if f.Binary {
+			fmt.Fprintf(&b, " %-*s | Bin\n", width, f.Path)
+			continue
+		}
+		fmt.Fprintf(&b, " %-*s | +%d -%d\n", width, f.Path, f.Added, f.Deleted)
+		added += f.Added
+		deleted += f.Deleted
+	}
+	fmt.Fprintf(&b, " %d %s changed, %d insertions(+), %d deletions(-)\n", len(files), plural(len(files), "file"), added, deleted)
+	return b.String()
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// PrepareDiff shrinks diff to fit the budget in tokens. Lock, generated
+// and binary files are always stubbed. If that is not enough each file is
+// cut to its share of the budget, and as a last resort the result is a
+// stat summary followed by as many of the largest hunks as fit.
+func PrepareDiff(diff string, budget int) string {
+	files := ParseDiff(diff)
+	if len(files) == 0 {
+		return diff
+	}
+
+	parts := make([]string, len(files))
+	for i, f := range files {
+		if f.Binary || f.Generated {
+			parts[i] = f.stub()
+		} else {
+			parts[i] = f.Text()
+		}
+	}
+	out := strings.Join(parts, "")
+	if EstimateTokens(out) <= budget {
+		return out
+	}
+
+	// Give every file an equal share, letting small files donate what
+	// they do not use to the larger ones
+	var large []int
+	remaining := budget
+	for i, f := range files {
+		if f.Binary || f.Generated {
+			remaining -= EstimateTokens(parts[i])
+		} else {
+			large = append(large, i)
+		}
+	}
+	sort.Slice(large, func(a, b int) bool {
+		return len(parts[large[a]]) < len(parts[large[b]])
+	})
+	for n, i := range large {
+		share := remaining / (len(large) - n)
+		if EstimateTokens(parts[i]) > share {
+			parts[i] = truncateFile(files[i], share)
+		}
+		remaining -= EstimateTokens(parts[i])
+	}
+	out = strings.Join(parts, "")
+	if EstimateTokens(out) <= budget {
+		return out
+	}
+
+	return statWithTopHunks(files, budget)
+}
+
+// truncateFile keeps the header and as much of the hunks as fits in
+// budget tokens, cutting the last hunk at a line boundary
+func truncateFile(f *FileDiff, budget int) string {
+	var b strings.Builder
+	b.WriteString(f.Header)
+	limit := budget * 4
+	kept := 0
+	for _, h := range f.Hunks {
+		if b.Len()+len(h) <= limit {
+			b.WriteString(h)
+			kept++
+			continue
+		}
+		for _, line := range strings.SplitAfter(h, "\n") {
+			if b.Len()+len(line) > limit {
+				break
+			}
+			b.WriteString(line)
+		}
+		break
+	}
+	if kept < len(f.Hunks) {
+		fmt.Fprintf(&b, "... truncated, %d of %d hunks shown in full (+%d -%d lines in file)\n", kept, len(f.Hunks), f.Added, f.Deleted)
+	}
+	return b.String()
+}
+
+// statWithTopHunks lists every file, then adds the biggest hunks from
+// source files until the budget runs out
+func statWithTopHunks(files []*FileDiff, budget int) string {
+	var b strings.Builder
+	b.WriteString("Diff too large to include in full. Summary:\n\n")
+	b.WriteString(DiffStat(files))
+	b.WriteString("\nLargest changes:\n\n")
+
+	type ranked struct {
+		file *FileDiff
+		hunk string
+	}
+	var hunks []ranked
+	for _, f := range files {
+		if f.Binary || f.Generated {
+			continue
+		}
+		for _, h := range f.Hunks {
+			hunks = append(hunks, ranked{f, h})
+		}
+	}
+	sort.SliceStable(hunks, func(i, j int) bool {
+		return len(hunks[i].hunk) > len(hunks[j].hunk)
+	})
+
+	for _, h := range hunks {
+		entry := fmt.Sprintf("--- %s\n%s", h.file.Path, h.hunk)
+		if EstimateTokens(b.String()+entry) > budget {
+			continue
+		}
+		b.WriteString(entry)
+	}
+	return b.String()
+}
+
+// diffForPrompt returns the diff, or what stands in for it, to put in a
+// prompt. Diffs over budget are shrunk with PrepareDiff, or in map-reduce
+// mode replaced by per-file summaries.
+func (g *CommitMessageGenerator) diffForPrompt(ctx context.Context, diff string) (string, error) {
+	budget := g.opts.Diff.budget()
+	if EstimateTokens(diff) <= budget {
+		return diff, nil
+	}
+	if g.opts.Diff.Mode != DiffModeMapReduce {
+		return PrepareDiff(diff, budget), nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.preparedFrom == diff {
+		return g.prepared, nil
+	}
+	prepared, err := g.summarizeFiles(ctx, diff, budget)
+	if err != nil {
+		return "", err
+	}
+	g.preparedFrom, g.prepared = diff, prepared
+	return prepared, nil
+}
+
+// summarizeFiles asks the model for a one-line summary of each file's
+// changes, running a few requests at a time
+func (g *CommitMessageGenerator) summarizeFiles(ctx context.Context, diff string, budget int) (string, error) {
+	files := ParseDiff(diff)
+	summaries := make([]string, len(files))
+	errs := make([]error, len(files))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, mapConcurrency)
+	for i, f := range files {
+		if f.Binary || f.Generated {
+			summaries[i] = strings.TrimSpace(f.stub())
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, f *FileDiff) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			text := f.Text()
+			if EstimateTokens(text) > budget {
+				text = truncateFile(f, budget)
+			}
+			resp, err := g.gen.Generate(ctx, Request{
+				System: `You summarize code changes. Given the diff of a single file, describe what changed
+and why in one or two plain sentences. Do not restate the file name.`,
+				User:        text,
+				MaxTokens:   100,
+				Temperature: 0.2,
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to summarize %s: %w", f.Path, err)
+				return
+			}
+			summaries[i] = fmt.Sprintf("%s: %s", f.Path, strings.TrimSpace(resp))
+		}(i, f)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("The diff is too large to include; per-file summaries follow.\n\n")
+	b.WriteString(DiffStat(files))
+	b.WriteString("\n")
+	for _, s := range summaries {
+		b.WriteString("- " + s + "\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saint/ghquick/internal/git"
+)
+
+// HeuristicMessage builds a commit message from the diff's file list and
+// stat without calling a model: the type comes from what kind of files
+// changed, the scope from where they live and the description from how.
+// It is deterministic and always returns a valid message, so it works as
+// a fallback when the provider is unreachable.
+func HeuristicMessage(diff string, opts Options) string {
+	files := ParseDiff(diff)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+
+	description := describeChange(files)
+	subject := ""
+	if opts.Style.FreeForm {
+		first, size := utf8.DecodeRuneInString(description)
+		subject = strings.ToUpper(string(first)) + description[size:]
+	} else {
+		s := &Subject{
+			Type:        allowedType(inferType(files), opts.Rules),
+			Scope:       InferScope(paths, opts.Rules.Scopes),
+			Description: description,
+		}
+		if s.Scope == s.Type {
+			s.Scope = ""
+		}
+		subject = s.String()
+		if utf8.RuneCountInString(subject) > MaxSubjectLength {
+			s.Scope = ""
+			subject = s.String()
+		}
+	}
+
+	suffix := ""
+	if t := opts.Style.Ticket; t != "" {
+		suffix = " (" + t + ")"
+	}
+	subject = truncateSubject(subject, MaxSubjectLength-utf8.RuneCountInString(suffix)) + suffix
+
+	msg := &CommitMessage{Subject: subject}
+	if opts.Format == FormatFull && len(files) > 0 {
+		msg.Body = strings.TrimRight(DiffStat(files), "\n")
+	}
+	return msg.String()
+}
+
+// inferType picks a Conventional Commits type from the kinds of files in
+// the change. Mixed changes are a feat when they add source files and a
+// chore otherwise, since nothing here can tell a fix from a tweak.
+func inferType(files []*FileDiff) string {
+	if len(files) == 0 {
+		return "chore"
+	}
+
+	kinds := make(map[string]bool)
+	for _, f := range files {
+		kinds[fileKind(f.Path)] = true
+	}
+	if len(kinds) == 1 {
+		for kind := range kinds {
+			if kind != "" {
+				return kind
+			}
+		}
+	}
+
+	created, removed := 0, 0
+	for _, f := range files {
+		if fileKind(f.Path) != "" {
+			continue
+		}
+		switch {
+		case f.Created:
+			created++
+		case f.Removed:
+			removed++
+		}
+	}
+	switch {
+	case created > 0:
+		return "feat"
+	case removed > 0 && removed == len(files):
+		return "refactor"
+	}
+	return "chore"
+}
+
+// fileKind classifies a path as docs, test, build or ci, or "" for source
+func fileKind(p string) string {
+	base := path.Base(p)
+	lower := strings.ToLower(base)
+	dirs := "/" + path.Dir(p) + "/"
+
+	switch {
+	case strings.HasPrefix(p, ".github/workflows/"), base == ".gitlab-ci.yml",
+		strings.HasPrefix(p, ".circleci/"), base == "Jenkinsfile", base == ".travis.yml":
+		return "ci"
+	case strings.HasSuffix(base, "_test.go"), strings.Contains(lower, ".test."), strings.Contains(lower, ".spec."),
+		strings.HasPrefix(lower, "test_"), strings.Contains(dirs, "/testdata/"),
+		strings.Contains(dirs, "/test/"), strings.Contains(dirs, "/tests/"), strings.Contains(dirs, "/__tests__/"):
+		return "test"
+	case strings.HasPrefix(p, "docs/"), strings.HasPrefix(p, "doc/"),
+		strings.HasSuffix(lower, ".md"), strings.HasSuffix(lower, ".rst"), strings.HasSuffix(lower, ".adoc"),
+		strings.HasPrefix(base, "LICENSE"), strings.HasPrefix(base, "CHANGELOG"):
+		return "docs"
+	case git.IsLockfile(base), base == "go.mod", base == "package.json", base == "Cargo.toml",
+		base == "pyproject.toml", base == "requirements.txt", base == "Gemfile",
+		base == "Makefile", base == "Dockerfile", strings.HasPrefix(base, "Dockerfile."),
+		base == ".goreleaser.yml", base == ".goreleaser.yaml":
+		return "build"
+	}
+	return ""
+}
+
+// allowedType falls back to chore, or the first allowed type, when the
+// project doesn't allow the inferred one
+func allowedType(t string, rules Rules) string {
+	types := rules.types()
+	if contains(types, t) {
+		return t
+	}
+	if contains(types, "chore") {
+		return "chore"
+	}
+	return types[0]
+}
+
+// describeChange says what happened to which files, e.g. "add parser.go"
+// or "update 3 files in internal/git"
+func describeChange(files []*FileDiff) string {
+	if len(files) == 0 {
+		return "update files"
+	}
+
+	verb := "update"
+	switch {
+	case all(files, func(f *FileDiff) bool { return f.Created }):
+		verb = "add"
+	case all(files, func(f *FileDiff) bool { return f.Removed }):
+		verb = "remove"
+	case all(files, func(f *FileDiff) bool { return f.RenamedFrom != "" && f.Added == 0 && f.Deleted == 0 }):
+		verb = "rename"
+	}
+
+	if len(files) == 1 {
+		f := files[0]
+		if verb == "rename" {
+			return fmt.Sprintf("rename %s to %s", path.Base(f.RenamedFrom), path.Base(f.Path))
+		}
+		return fmt.Sprintf("%s %s", verb, path.Base(f.Path))
+	}
+
+	var dirs []string
+	for _, f := range files {
+		if d := path.Dir(f.Path); !contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	switch {
+	case len(dirs) == 1 && dirs[0] != ".":
+		return fmt.Sprintf("%s %d files in %s", verb, len(files), dirs[0])
+	case len(dirs) == 2 && dirs[0] != "." && dirs[1] != ".":
+		return fmt.Sprintf("%s %d files in %s and %s", verb, len(files), dirs[0], dirs[1])
+	}
+	return fmt.Sprintf("%s %d files", verb, len(files))
+}
+
+func all(files []*FileDiff, pred func(*FileDiff) bool) bool {
+	for _, f := range files {
+		if !pred(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateSubject shortens a subject to limit characters with an ellipsis
+func truncateSubject(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
@@ -0,0 +1,215 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MessageFormat selects how much of a commit message is generated
+type MessageFormat string
+
+const (
+	// FormatSubject is a single subject line
+	FormatSubject MessageFormat = "subject"
+	// FormatFull adds a wrapped body and footers such as BREAKING CHANGE
+	FormatFull MessageFormat = "full"
+)
+
+const (
+	// MaxSubjectLength is the longest subject git tooling displays untruncated
+	MaxSubjectLength = 72
+	// bodyWidth is the column the body is wrapped at
+	bodyWidth = 72
+)
+
+// Trailer is a "Token: value" footer line such as Refs or Co-authored-by
+type Trailer struct {
+	Token string
+	Value string
+}
+
+// CommitMessage is a commit message split into its parts
+type CommitMessage struct {
+	Subject  string
+	Body     string
+	Trailers []Trailer
+}
+
+// trailerPattern matches footer lines, including the "Refs #123" form and
+// the space in BREAKING CHANGE that the conventional commits spec allows
+var trailerPattern = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][A-Za-z0-9-]*)(: | #)(.*)$`)
+
+// ParseCommitMessage splits text into subject, body and trailers. The
+// trailers are the last paragraph if every line in it is a footer;
+// indented lines continue the previous footer.
+func ParseCommitMessage(text string) (*CommitMessage, error) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil, errors.New("empty commit message")
+	}
+
+	subject, rest, _ := strings.Cut(text, "\n")
+	m := &CommitMessage{Subject: strings.TrimSpace(subject)}
+
+	var paragraphs []string
+	for _, p := range strings.Split(strings.TrimSpace(rest), "\n\n") {
+		if p = strings.Trim(p, "\n"); strings.TrimSpace(p) != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	if n := len(paragraphs); n > 0 {
+		if trailers, ok := parseTrailers(paragraphs[n-1]); ok {
+			m.Trailers = trailers
+			paragraphs = paragraphs[:n-1]
+		}
+	}
+	m.Body = strings.Join(paragraphs, "\n\n")
+	return m, nil
+}
+
+func parseTrailers(paragraph string) ([]Trailer, bool) {
+	var trailers []Trailer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if len(trailers) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			last := &trailers[len(trailers)-1]
+			last.Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		match := trailerPattern.FindStringSubmatch(strings.TrimRight(line, " "))
+		if match == nil {
+			return nil, false
+		}
+		value := strings.TrimSpace(match[3])
+		if match[2] == " #" {
+			value = "#" + value
+		}
+		trailers = append(trailers, Trailer{Token: match[1], Value: value})
+	}
+	return trailers, len(trailers) > 0
+}
+
+// Validate checks the structural rules every generated message must meet
+func (m *CommitMessage) Validate() error {
+	if m.Subject == "" {
+		return errors.New("the subject line is empty")
+	}
+	if n := utf8.RuneCountInString(m.Subject); n > MaxSubjectLength {
+		return fmt.Errorf("the subject line is %d characters, the limit is %d", n, MaxSubjectLength)
+	}
+	for _, t := range m.Trailers {
+		if t.Value == "" {
+			return fmt.Errorf("the %s footer has no value", t.Token)
+		}
+	}
+	return nil
+}
+
+// AddTrailer appends a trailer unless an identical one is already present
+func (m *CommitMessage) AddTrailer(token, value string) {
+	for _, t := range m.Trailers {
+		if strings.EqualFold(t.Token, token) && t.Value == value {
+			return
+		}
+	}
+	m.Trailers = append(m.Trailers, Trailer{Token: token, Value: value})
+}
+
+// String formats the message with the body wrapped at 72 columns
+func (m *CommitMessage) String() string {
+	var b strings.Builder
+	b.WriteString(m.Subject)
+	if m.Body != "" {
+		b.WriteString("\n\n")
+		b.WriteString(wrapBody(m.Body, bodyWidth))
+	}
+	if len(m.Trailers) > 0 {
+		b.WriteString("\n")
+		for _, t := range m.Trailers {
+			b.WriteString("\n")
+			if strings.HasPrefix(t.Value, "#") && t.Token != "BREAKING CHANGE" {
+				b.WriteString(t.Token + " " + t.Value)
+			} else {
+				b.WriteString(t.Token + ": " + t.Value)
+			}
+		}
+	}
+	return b.String()
+}
+
+// ParseTrailer parses a "Token: value" argument such as a --trailer flag
+func ParseTrailer(s string) (Trailer, error) {
+	match := trailerPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil || match[2] != ": " || strings.TrimSpace(match[3]) == "" {
+		return Trailer{}, fmt.Errorf("invalid trailer %q (want \"Token: value\")", s)
+	}
+	return Trailer{Token: match[1], Value: strings.TrimSpace(match[3])}, nil
+}
+
+// listItem matches the start of a bullet or numbered list item
+var listItem = regexp.MustCompile(`^([-*]|\d+[.)]) `)
+
+// wrapBody wraps prose and list items at width. Indented lines, such as
+// code, are left alone.
+func wrapBody(body string, width int) string {
+	paragraphs := strings.Split(body, "\n\n")
+	for i, p := range paragraphs {
+		var out []string
+		var item []string
+		indent := ""
+		flush := func() {
+			if len(item) > 0 {
+				out = append(out, wrapText(strings.Join(item, " "), width, indent)...)
+			}
+			item, indent = nil, ""
+		}
+
+		for _, line := range strings.Split(p, "\n") {
+			switch {
+			case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+				if indent != "" && len(item) > 0 {
+					// Continuation of a list item
+					item = append(item, strings.TrimSpace(line))
+					continue
+				}
+				flush()
+				out = append(out, line)
+			case listItem.MatchString(line):
+				flush()
+				item = []string{line}
+				indent = strings.Repeat(" ", len(listItem.FindString(line)))
+			default:
+				if indent != "" {
+					flush()
+				}
+				item = append(item, strings.TrimSpace(line))
+			}
+		}
+		flush()
+		paragraphs[i] = strings.Join(out, "\n")
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// wrapText greedily fills lines up to width, indenting all but the first
+func wrapText(text string, width int, indent string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width:
+			lines = append(lines, line)
+			line = indent + word
+		default:
+			line += " " + word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
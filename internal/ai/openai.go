@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// openAIGenerator talks to OpenAI, Azure OpenAI, or any server that
+// implements the OpenAI chat completions API
+type openAIGenerator struct {
+	client *openai.Client
+	model  string
+}
+
+func newOpenAIGenerator(cfg ProviderConfig) *openAIGenerator {
+	var clientConfig openai.ClientConfig
+	switch cfg.Provider {
+	case ProviderAzure:
+		clientConfig = openai.DefaultAzureConfig(cfg.APIKey, cfg.BaseURL)
+		if cfg.APIVersion != "" {
+			clientConfig.APIVersion = cfg.APIVersion
+		}
+		// The model is the deployment name, use it verbatim
+		clientConfig.AzureModelMapperFunc = func(model string) string { return model }
+	default:
+		clientConfig = openai.DefaultConfig(cfg.APIKey)
+		if cfg.BaseURL != "" {
+			clientConfig.BaseURL = cfg.BaseURL
+		}
+	}
+
+	return &openAIGenerator{
+		client: openai.NewClientWithConfig(clientConfig),
+		model:  cfg.Model,
+	}
+}
+
+func (g *openAIGenerator) Generate(ctx context.Context, req Request) (string, error) {
+	resp, err := g.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: g.model,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: req.System,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: req.User,
+				},
+			},
+			MaxTokens:   req.MaxTokens,
+			Temperature: req.Temperature,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("empty response from model %s", g.model)
+	}
+	return resp.Choices[0].Message.Content, nil
+}
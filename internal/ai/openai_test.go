@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatRequest is the part of a chat completions request the tests check
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+	MaxTokens   int     `json:"max_tokens"`
+	Temperature float32 `json:"temperature"`
+}
+
+const chatResponse = `{"choices":[{"index":0,"message":{"role":"assistant","content":"feat: add parser"}}]}`
+
+func TestOpenAIGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      ProviderConfig
+		path     string
+		query    string
+		header   string
+		wantAuth string
+		model    string
+	}{
+		{
+			name:     "openai",
+			cfg:      ProviderConfig{Provider: ProviderOpenAI, APIKey: "sk-test", BaseURL: "/v1"},
+			path:     "/v1/chat/completions",
+			header:   "Authorization",
+			wantAuth: "Bearer sk-test",
+			model:    DefaultOpenAIModel,
+		},
+		{
+			name:     "azure",
+			cfg:      ProviderConfig{Provider: ProviderAzure, APIKey: "az-key", Model: "my-deployment", APIVersion: "2024-02-01"},
+			path:     "/openai/deployments/my-deployment/chat/completions",
+			query:    "api-version=2024-02-01",
+			header:   "api-key",
+			wantAuth: "az-key",
+			model:    "my-deployment",
+		},
+		{
+			name:   "compatible",
+			cfg:    ProviderConfig{Provider: ProviderCompatible, BaseURL: "/v1", Model: "llama3"},
+			path:   "/v1/chat/completions",
+			header: "Authorization",
+			// go-openai always sends the header, with an empty key here
+			wantAuth: "Bearer",
+			model:    "llama3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got chatRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("method = %s, want POST", r.Method)
+				}
+				if r.URL.Path != tt.path {
+					t.Errorf("path = %s, want %s", r.URL.Path, tt.path)
+				}
+				if r.URL.RawQuery != tt.query {
+					t.Errorf("query = %q, want %q", r.URL.RawQuery, tt.query)
+				}
+				if auth := strings.TrimSpace(r.Header.Get(tt.header)); auth != tt.wantAuth {
+					t.Errorf("%s = %q, want %q", tt.header, auth, tt.wantAuth)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(chatResponse))
+			}))
+			defer srv.Close()
+
+			tt.cfg.BaseURL = srv.URL + tt.cfg.BaseURL
+			gen, err := NewMessageGenerator(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, err := gen.Generate(context.Background(), Request{System: "be brief", User: "the diff", MaxTokens: 100, Temperature: 0.5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != "feat: add parser" {
+				t.Errorf("text = %q", text)
+			}
+
+			if got.Model != tt.model {
+				t.Errorf("model = %q, want %q", got.Model, tt.model)
+			}
+			if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[0].Content != "be brief" ||
+				got.Messages[1].Role != "user" || got.Messages[1].Content != "the diff" {
+				t.Errorf("messages = %+v", got.Messages)
+			}
+			if got.MaxTokens != 100 || got.Temperature != 0.5 {
+				t.Errorf("max_tokens = %d, temperature = %v", got.MaxTokens, got.Temperature)
+			}
+		})
+	}
+}
+
+func TestOpenAIGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"api error", http.StatusUnauthorized, `{"error":{"message":"Incorrect API key","type":"invalid_request_error"}}`, "Incorrect API key"},
+		{"no choices", http.StatusOK, `{"choices":[]}`, "empty response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			gen, err := NewMessageGenerator(ProviderConfig{APIKey: "sk-test", BaseURL: srv.URL + "/v1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = gen.Generate(context.Background(), Request{User: "the diff"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewMessageGeneratorValidation(t *testing.T) {
+	tests := []ProviderConfig{
+		{Provider: ProviderAzure, Model: "deployment"},
+		{Provider: ProviderAzure, BaseURL: "https://example.openai.azure.com"},
+		{Provider: ProviderCompatible, BaseURL: "http://localhost:11434/v1"},
+		{Provider: "gemini"},
+	}
+	for _, cfg := range tests {
+		if _, err := NewMessageGenerator(cfg); err == nil {
+			t.Errorf("NewMessageGenerator(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// Supported providers
+const (
+	ProviderOpenAI     = "openai"
+	ProviderAzure      = "azure"
+	ProviderAnthropic  = "anthropic"
+	ProviderCompatible = "compatible"
+)
+
+// Providers lists the provider names accepted in config
+var Providers = []string{ProviderOpenAI, ProviderAzure, ProviderAnthropic, ProviderCompatible}
+
+// Default models for providers that have a sensible one; Azure deployments
+// and self-hosted servers must name theirs
+const (
+	DefaultOpenAIModel    = "gpt-4-1106-preview"
+	DefaultAnthropicModel = "claude-3-5-sonnet-latest"
+)
+
+// Request is a single-turn chat prompt
+type Request struct {
+	System      string
+	User        string
+	MaxTokens   int
+	Temperature float32
+}
+
+// MessageGenerator is a chat model backend that turns a prompt into text
+type MessageGenerator interface {
+	Generate(ctx context.Context, req Request) (string, error)
+}
+
+// ProviderConfig selects and configures a MessageGenerator
+type ProviderConfig struct {
+	// Provider is one of Providers; empty means OpenAI
+	Provider string
+	APIKey   string
+	// BaseURL overrides the API endpoint. It is required for Azure (the
+	// resource endpoint) and compatible servers such as Ollama
+	// (http://localhost:11434/v1) or llama.cpp (http://localhost:8080/v1).
+	BaseURL string
+	// Model is the model name, or the deployment name on Azure
+	Model string
+	// APIVersion is the Azure OpenAI API version
+	APIVersion string
+}
+
+// NewMessageGenerator builds the generator for cfg.Provider
+func NewMessageGenerator(cfg ProviderConfig) (MessageGenerator, error) {
+	switch cfg.Provider {
+	case "", ProviderOpenAI:
+		if cfg.Model == "" {
+			cfg.Model = DefaultOpenAIModel
+		}
+		return newOpenAIGenerator(cfg), nil
+	case ProviderAzure:
+		if cfg.BaseURL == "" || cfg.Model == "" {
+			return nil, fmt.Errorf("the azure provider needs a base URL and a deployment name as the model")
+		}
+		return newOpenAIGenerator(cfg), nil
+	case ProviderCompatible:
+		if cfg.BaseURL == "" || cfg.Model == "" {
+			return nil, fmt.Errorf("the compatible provider needs a base URL and a model")
+		}
+		return newOpenAIGenerator(cfg), nil
+	case ProviderAnthropic:
+		if cfg.Model == "" {
+			cfg.Model = DefaultAnthropicModel
+		}
+		return newAnthropicGenerator(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+}
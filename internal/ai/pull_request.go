@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PullRequestText is a generated pull request title and description
+type PullRequestText struct {
+	Title string
+	Body  string
+}
+
+func (g *CommitMessageGenerator) GeneratePullRequest(ctx context.Context, commitLog, diff string) (*PullRequestText, error) {
+	systemPrompt := `You write GitHub pull request descriptions. Given the commit messages and diff of a branch,
+reply with a title on the first line (under 72 characters, no prefix or quotes), then a blank line,
+then a markdown body: a short summary paragraph followed by a bullet list of notable changes.`
+
+	diff, err := g.diffForPrompt(ctx, diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pull request description: %w", err)
+	}
+
+	resp, err := g.gen.Generate(ctx, Request{
+		System:      systemPrompt,
+		User:        fmt.Sprintf("Commits:\n\n%s\n\nDiff:\n\n%s", commitLog, diff),
+		MaxTokens:   500,
+		Temperature: 0.3,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pull request description: %w", err)
+	}
+
+	title, body, _ := strings.Cut(strings.TrimSpace(resp), "\n")
+	title = strings.Trim(strings.TrimSpace(title), "#*\"` ")
+	if title == "" {
+		return nil, fmt.Errorf("failed to generate pull request description: empty title")
+	}
+	return &PullRequestText{Title: title, Body: strings.TrimSpace(body)}, nil
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxExamples is how many recent subjects are shown to the model
+const maxExamples = 10
+
+// Style describes how a repository writes commit messages
+type Style struct {
+	// FreeForm turns off Conventional Commits parsing and rules, for
+	// repositories whose history doesn't use them
+	FreeForm bool
+	// Examples are recent subjects from the repository, newest first
+	Examples []string
+	// Ticket is the issue key taken from the branch name, e.g. ABC-123;
+	// generated subjects must mention it
+	Ticket string
+	Branch string
+}
+
+var (
+	ticketPattern   = regexp.MustCompile(`\b([A-Z][A-Z0-9]+)-(\d+)\b`)
+	branchTicketKey = regexp.MustCompile(`(?:^|[/_.-])([A-Za-z][A-Za-z0-9]+)-(\d+)(?:$|[/_.-])`)
+)
+
+// DetectStyle infers a Style from recent commit subjects, newest first,
+// and the current branch name. History that mostly follows Conventional
+// Commits keeps the default style; anything else is free-form, matched
+// through the examples. Repositories with little history keep the default.
+func DetectStyle(subjects []string, branch string, rules Rules) Style {
+	style := Style{Branch: branch}
+	considered, conventional := 0, 0
+	for _, s := range subjects {
+		s = strings.TrimSpace(s)
+		if s == "" || len(s) > 2*MaxSubjectLength {
+			continue
+		}
+		considered++
+		if subject, err := ParseSubject(s); err == nil && contains(rules.types(), strings.ToLower(subject.Type)) {
+			conventional++
+		}
+		if len(style.Examples) < maxExamples && !contains(style.Examples, s) {
+			style.Examples = append(style.Examples, s)
+		}
+	}
+	if len(style.Examples) >= 3 && conventional*2 < considered {
+		style.FreeForm = true
+	}
+	style.Ticket = branchTicket(branch, subjects)
+	return style
+}
+
+// branchTicket extracts an issue key such as ABC-123 from a branch name.
+// Lowercase keys (feature/abc-123-login) only count when the project key
+// appears in the history, so branches like fix-2 aren't mistaken for one.
+func branchTicket(branch string, subjects []string) string {
+	m := branchTicketKey.FindStringSubmatch(branch)
+	if m == nil {
+		return ""
+	}
+	key := strings.ToUpper(m[1])
+	if m[1] == key {
+		return key + "-" + m[2]
+	}
+	for _, s := range subjects {
+		for _, t := range ticketPattern.FindAllStringSubmatch(s, -1) {
+			if t[1] == key {
+				return key + "-" + m[2]
+			}
+		}
+	}
+	return ""
+}
+
+// check enforces the parts of the style that can be verified
+func (s Style) check(subject string) error {
+	if s.Ticket != "" && !strings.Contains(strings.ToUpper(subject), s.Ticket) {
+		return fmt.Errorf("the subject must reference ticket %s", s.Ticket)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDetectStyleFreeForm(t *testing.T) {
+	repeat := func(n int, format string) []string {
+		var out []string
+		for i := 0; i < n; i++ {
+			out = append(out, fmt.Sprintf(format, i))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		subjects []string
+		want     bool
+	}{
+		{
+			name:     "conventional history",
+			subjects: repeat(20, "feat: add feature %d"),
+			want:     false,
+		},
+		{
+			name:     "free-form history",
+			subjects: repeat(20, "Add feature %d"),
+			want:     true,
+		},
+		{
+			// More conventional subjects than examples must not outweigh
+			// a mostly free-form history
+			name:     "mostly free-form beyond the examples",
+			subjects: append(repeat(8, "fix: bug %d"), repeat(12, "Update thing %d")...),
+			want:     true,
+		},
+		{
+			name:     "mostly conventional beyond the examples",
+			subjects: append(repeat(8, "Update thing %d"), repeat(12, "fix: bug %d")...),
+			want:     false,
+		},
+		{
+			name:     "repeated free-form subjects",
+			subjects: append([]string{"feat: a", "feat: b", "feat: c"}, "WIP", "WIP", "WIP", "WIP", "WIP"),
+			want:     true,
+		},
+		{
+			name:     "too little history",
+			subjects: []string{"Initial commit", "Update README"},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := DetectStyle(tt.subjects, "main", Rules{})
+			if style.FreeForm != tt.want {
+				t.Errorf("FreeForm = %v, want %v (examples %d)", style.FreeForm, tt.want, len(style.Examples))
+			}
+			if len(style.Examples) > maxExamples {
+				t.Errorf("got %d examples, want at most %d", len(style.Examples), maxExamples)
+			}
+		})
+	}
+}
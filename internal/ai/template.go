@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// DefaultPromptFile is where a repository keeps its commit prompt
+// template, relative to the repository root
+const DefaultPromptFile = ".ghquick/prompts/commit.tmpl"
+
+// PromptData is the data commit prompt templates are executed with
+type PromptData struct {
+	// Diff is the staged diff after fitting it to the token budget
+	Diff string
+	// Stat summarizes the diff like git diff --stat
+	Stat string
+	// Files lists the changed paths
+	Files  []string
+	Branch string
+	// RecentLog holds recent commit subjects, newest first
+	RecentLog []string
+	// Ticket is the issue key from the branch name, if any
+	Ticket string
+	// Scope is the scope inferred from the changed paths, if any
+	Scope string
+	// Hint is the user's guidance when regenerating
+	Hint string
+	// Conventional is false when the repository doesn't use Conventional Commits
+	Conventional bool
+	Types        []string
+	Scopes       []string
+}
+
+// defaultCommitTemplate is the built-in user prompt
+const defaultCommitTemplate = `Generate a commit message for this diff:
+
+{{.Diff}}
+{{- if .RecentLog}}
+
+Recent commit subjects in this repository; match their conventions (prefixes, scopes, emoji, tense, capitalization):
+{{- range .RecentLog}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .Ticket}}
+
+The subject must reference ticket {{.Ticket}}, placed the way the examples reference tickets (at the end in parentheses if they don't).
+{{- end}}
+{{- if .Scope}}
+
+All changes are under "{{.Scope}}"; use it as the scope unless another fits better.
+{{- end}}
+{{- if .Hint}}
+
+Guidance from the author: {{.Hint}}
+{{- end}}`
+
+// PromptTemplate renders the commit prompt. The template's main body is
+// the user message; a {{define "system"}} block, if present, replaces the
+// built-in system prompt.
+type PromptTemplate struct {
+	tmpl *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lines": func(s string) []string { return strings.Split(strings.TrimRight(s, "\n"), "\n") },
+	"trim":  strings.TrimSpace,
+}
+
+// ParsePromptTemplate parses a text/template prompt
+func ParsePromptTemplate(name, text string) (*PromptTemplate, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+	return &PromptTemplate{tmpl: tmpl}, nil
+}
+
+// LoadPromptTemplate reads and parses the prompt template at path
+func LoadPromptTemplate(path string) (*PromptTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return ParsePromptTemplate(path, string(data))
+}
+
+var defaultPromptTemplate = template.Must(template.New("commit").Funcs(templateFuncs).Parse(defaultCommitTemplate))
+
+// render executes the template, returning the system prompt override (if
+// the template defines one) and the user message
+func (t *PromptTemplate) render(data PromptData) (system, user string, err error) {
+	tmpl := defaultPromptTemplate
+	if t != nil {
+		tmpl = t.tmpl
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	user = strings.TrimSpace(b.String())
+
+	if sys := tmpl.Lookup("system"); sys != nil {
+		b.Reset()
+		if err := sys.Execute(&b, data); err != nil {
+			return "", "", fmt.Errorf("failed to render prompt template: %w", err)
+		}
+		system = strings.TrimSpace(b.String())
+	}
+	return system, user, nil
+}
@@ -0,0 +1,37 @@
+//go:build !unix
+
+package cache
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// staleLock is how old a lock file must be before it is assumed to belong
+// to a process that died holding it
+const staleLock = 30 * time.Second
+
+// lockFile holds path as an exclusively created file until released.
+// Without flock, shared locks are exclusive too.
+func lockFile(path string, exclusive bool) (func(), error) {
+	deadline := time.Now().Add(staleLock)
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for lock")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
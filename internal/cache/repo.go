@@ -1,52 +1,51 @@
 package cache
 
-import (
-	"sync"
-	"time"
-)
+import "time"
 
+// RepoInfo is what ghquick knows about a GitHub repository
 type RepoInfo struct {
-	Name      string
-	Path      string
-	Remote    string
-	Branch    string
-	UpdatedAt time.Time
+	Host  string `json:"host"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// Remote is the repository's clone URL
+	Remote        string `json:"remote"`
+	DefaultBranch string `json:"default_branch"`
+	// Visibility is public, private or internal
+	Visibility string    `json:"visibility"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+const (
+	// repoCacheTTL and repoCacheSize bound the in-memory repository cache
+	repoCacheTTL  = 5 * time.Minute
+	repoCacheSize = 256
+)
+
+// RepoCache keeps RepoInfo in memory for the life of the process, keyed
+// by repository path
 type RepoCache struct {
-	mu    sync.RWMutex
-	cache map[string]*RepoInfo
-	ttl   time.Duration
+	cache *TTLCache[string, *RepoInfo]
 }
 
 func NewRepoCache() *RepoCache {
-	return &RepoCache{
-		cache: make(map[string]*RepoInfo),
-		ttl:   5 * time.Minute, // Cache TTL of 5 minutes
-	}
+	return &RepoCache{cache: NewTTLCache[string, *RepoInfo](repoCacheTTL, repoCacheSize)}
 }
 
 func (c *RepoCache) Get(path string) (*RepoInfo, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	info, exists := c.cache[path]
-	if !exists {
-		return nil, false
-	}
-
-	if time.Since(info.UpdatedAt) > c.ttl {
-		delete(c.cache, path)
-		return nil, false
-	}
-
-	return info, true
+	return c.cache.Get(path)
 }
 
 func (c *RepoCache) Set(path string, info *RepoInfo) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	info.UpdatedAt = time.Now()
-	c.cache[path] = info
+	c.cache.Set(path, info)
+}
+
+// Stats returns the cache's hit, miss and eviction counts
+func (c *RepoCache) Stats() Stats {
+	return c.cache.Stats()
+}
+
+// Close stops the cache's background eviction
+func (c *RepoCache) Close() {
+	c.cache.Close()
 }
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// RepoStoreFile is the file under the cache directory that repository
+// info persists in
+const RepoStoreFile = "repos.json"
+
+// ResponseStoreFile holds GitHub API responses kept for conditional
+// requests
+const ResponseStoreFile = "responses.json"
+
+// RepoStore persists RepoInfo across invocations, keyed by host, owner and
+// name, so repeat pushes to the same repository skip the API round-trip
+type RepoStore struct {
+	store *Store
+	ttl   time.Duration
+}
+
+// OpenRepoStore returns the repository store in the cache directory
+func OpenRepoStore(ttl time.Duration) (*RepoStore, error) {
+	store, err := OpenStore(RepoStoreFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewRepoStore(store, ttl), nil
+}
+
+// NewRepoStore returns a repository store backed by store
+func NewRepoStore(store *Store, ttl time.Duration) *RepoStore {
+	return &RepoStore{store: store, ttl: ttl}
+}
+
+func repoKey(host, owner, name string) string {
+	return strings.ToLower(host + "/" + owner + "/" + name)
+}
+
+// Lookup returns the entry for a repository, if any, and whether it is
+// still within the TTL
+func (s *RepoStore) Lookup(host, owner, name string) (info *RepoInfo, fresh bool, err error) {
+	err = s.store.Read(func(entries map[string]json.RawMessage) error {
+		raw, ok := entries[repoKey(host, owner, name)]
+		if !ok {
+			return nil
+		}
+		var entry RepoInfo
+		if json.Unmarshal(raw, &entry) != nil {
+			// Ignore entries written by an incompatible version
+			return nil
+		}
+		info = &entry
+		return nil
+	})
+	if err != nil || info == nil {
+		return nil, false, err
+	}
+	return info, time.Since(info.UpdatedAt) < s.ttl, nil
+}
+
+// Put stores info under the host, owner and name it was looked up by,
+// which differ from its own when the repository was renamed or
+// transferred, stamping it with the current time
+func (s *RepoStore) Put(host, owner, name string, info *RepoInfo) error {
+	info.UpdatedAt = time.Now()
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return s.store.Update(func(entries map[string]json.RawMessage) error {
+		entries[repoKey(host, owner, name)] = data
+		return nil
+	})
+}
+
+// Delete removes the entry for a repository
+func (s *RepoStore) Delete(host, owner, name string) error {
+	return s.store.Update(func(entries map[string]json.RawMessage) error {
+		delete(entries, repoKey(host, owner, name))
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory ghquick keeps its caches in,
+// $XDG_CACHE_HOME/ghquick or the platform's equivalent
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory: %w", err)
+	}
+	return filepath.Join(dir, "ghquick"), nil
+}
+
+// Store is a JSON file of keyed entries that several ghquick processes can
+// share. Reads take a shared lock and updates an exclusive one, and files
+// are replaced atomically so a crash never leaves half an update behind.
+type Store struct {
+	path string
+}
+
+// OpenStore returns the store in file name under the cache directory
+func OpenStore(name string) (*Store, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(filepath.Join(dir, name)), nil
+}
+
+// NewStore returns a store backed by the file at path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the file backing the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Read calls fn with the current entries
+func (s *Store) Read(fn func(entries map[string]json.RawMessage) error) error {
+	unlock, err := s.lock(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	return fn(entries)
+}
+
+// Update calls fn with the current entries and writes back whatever it
+// leaves in the map, holding the lock throughout so concurrent updates
+// don't overwrite each other
+func (s *Store) Update(fn func(entries map[string]json.RawMessage) error) error {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(entries); err != nil {
+		return err
+	}
+	return s.save(entries)
+}
+
+func (s *Store) lock(exclusive bool) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	unlock, err := lockFile(s.path+".lock", exclusive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock cache %s: %w", s.path, err)
+	}
+	return unlock, nil
+}
+
+// load reads the entries; a missing or corrupt file is an empty cache
+func (s *Store) load() (map[string]json.RawMessage, error) {
+	entries := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return make(map[string]json.RawMessage), nil
+	}
+	return entries, nil
+}
+
+func (s *Store) save(entries map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Stats counts how a TTLCache has been used
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	// Evictions counts entries dropped for expiring or for the size bound
+	Evictions uint64
+	Size      int
+}
+
+// TTLCache is an in-memory cache safe for concurrent use. Entries expire
+// ttl after they were set and, once maxSize is reached, the least
+// recently used entry makes room for a new one. Expired entries are
+// dropped on access and by a background sweep, which Close stops.
+type TTLCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	items   map[K]*list.Element
+	// lru orders entries from most to least recently used
+	lru   *list.List
+	stats Stats
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type ttlEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewTTLCache returns a cache whose entries live for ttl, holding at most
+// maxSize of them; zero for either means no limit
+func NewTTLCache[K comparable, V any](ttl time.Duration, maxSize int) *TTLCache[K, V] {
+	c := &TTLCache[K, V]{
+		ttl:     ttl,
+		maxSize: maxSize,
+		items:   make(map[K]*list.Element),
+		lru:     list.New(),
+		stop:    make(chan struct{}),
+	}
+	if ttl > 0 {
+		go c.sweep(sweepInterval(ttl))
+	}
+	return c
+}
+
+// sweepInterval runs the sweep often enough that expired entries don't
+// linger much past their TTL, without spinning for short ones
+func sweepInterval(ttl time.Duration) time.Duration {
+	if interval := ttl / 2; interval > time.Second {
+		return interval
+	}
+	return time.Second
+}
+
+// Get returns the value for key if it is present and not expired
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	entry := elem.Value.(*ttlEntry[K, V])
+	if c.expired(entry, time.Now()) {
+		c.remove(elem)
+		c.stats.Evictions++
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+// Set stores value for key, replacing any existing entry and resetting
+// its TTL
+func (c *TTLCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*ttlEntry[K, V])
+		entry.value = value
+		entry.expires = expires
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&ttlEntry[K, V]{key: key, value: value, expires: expires})
+	if c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Delete removes key from the cache
+func (c *TTLCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet swept
+func (c *TTLCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the cache's hit, miss and eviction counts
+func (c *TTLCache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	return stats
+}
+
+// Close stops the background sweep. The cache stays usable, with expired
+// entries then only dropped on access.
+func (c *TTLCache[K, V]) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+func (c *TTLCache[K, V]) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			c.evictExpired(now)
+		}
+	}
+}
+
+// evictExpired drops every entry expired at now
+func (c *TTLCache[K, V]) evictExpired(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if c.expired(elem.Value.(*ttlEntry[K, V]), now) {
+			c.remove(elem)
+			c.stats.Evictions++
+		}
+		elem = prev
+	}
+}
+
+func (c *TTLCache[K, V]) expired(entry *ttlEntry[K, V], now time.Time) bool {
+	return !entry.expires.IsZero() && now.After(entry.expires)
+}
+
+// remove unlinks elem; the caller holds mu
+func (c *TTLCache[K, V]) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*ttlEntry[K, V]).key)
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTTLCacheConcurrentAccess(t *testing.T) {
+	c := NewTTLCache[string, int](time.Minute, 16)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprint((g + i) % 32)
+				c.Set(key, i)
+				c.Get(key)
+				if i%5 == 0 {
+					c.Delete(key)
+				}
+				c.Len()
+				c.Stats()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n := c.Len(); n > 16 {
+		t.Errorf("Len() = %d, want at most 16", n)
+	}
+}
+
+func TestTTLCacheExpiry(t *testing.T) {
+	c := NewTTLCache[string, int](100*time.Millisecond, 0)
+	defer c.Close()
+
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v; want 1, true", v, ok)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) found an expired entry")
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len() = %d after expired Get, want 0", n)
+	}
+
+	// Setting again resets the TTL
+	c.Set("b", 1)
+	time.Sleep(60 * time.Millisecond)
+	c.Set("b", 2)
+	time.Sleep(60 * time.Millisecond)
+	if v, ok := c.Get("b"); !ok || v != 2 {
+		t.Errorf("Get(b) = %d, %v; want 2, true", v, ok)
+	}
+}
+
+func TestTTLCacheBackgroundSweep(t *testing.T) {
+	c := NewTTLCache[string, int](10*time.Millisecond, 0)
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		c.Set(fmt.Sprint(i), i)
+	}
+	// The sweep runs at least every second; nothing calls Get meanwhile
+	deadline := time.Now().Add(3 * time.Second)
+	for c.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d, expired entries were never swept", c.Len())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if s := c.Stats(); s.Evictions != 5 {
+		t.Errorf("Evictions = %d, want 5", s.Evictions)
+	}
+}
+
+func TestTTLCacheLRUEviction(t *testing.T) {
+	c := NewTTLCache[int, string](0, 3)
+	defer c.Close()
+
+	c.Set(1, "one")
+	c.Set(2, "two")
+	c.Set(3, "three")
+	// Using 1 makes 2 the least recently used
+	c.Get(1)
+	c.Set(4, "four")
+
+	if _, ok := c.Get(2); ok {
+		t.Error("2 should have been evicted as least recently used")
+	}
+	for _, key := range []int{1, 3, 4} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%d was evicted, want it kept", key)
+		}
+	}
+
+	// Replacing an entry doesn't grow the cache or evict anything
+	c.Set(3, "THREE")
+	if n := c.Len(); n != 3 {
+		t.Errorf("Len() = %d, want 3", n)
+	}
+	if v, _ := c.Get(3); v != "THREE" {
+		t.Errorf("Get(3) = %q, want THREE", v)
+	}
+}
+
+func TestTTLCacheStats(t *testing.T) {
+	c := NewTTLCache[string, int](20*time.Millisecond, 2)
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Get("a")       // hit
+	c.Get("missing") // miss
+	c.Set("b", 2)
+	c.Set("c", 3) // evicts a
+	c.Get("a")    // miss
+	time.Sleep(40 * time.Millisecond)
+	c.Get("b") // expired: miss and eviction
+
+	want := Stats{Hits: 1, Misses: 3, Evictions: 2, Size: 1}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRepoCache(t *testing.T) {
+	c := NewRepoCache()
+	defer c.Close()
+
+	if _, ok := c.Get("/src/app"); ok {
+		t.Fatal("empty cache returned an entry")
+	}
+	c.Set("/src/app", &RepoInfo{Owner: "acme", Name: "app"})
+	info, ok := c.Get("/src/app")
+	if !ok || info.Name != "app" || info.UpdatedAt.IsZero() {
+		t.Errorf("Get = %+v, %v", info, ok)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 1 hit and 1 miss", s)
+	}
+}
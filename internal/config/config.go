@@ -0,0 +1,533 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saint/ghquick/internal/ai"
+)
+
+// Config keys, shared by config files, environment variables and flags
+const (
+	KeyGitHubToken    = "github_token"
+	KeyGitHubUsername = "github_username"
+	KeyOpenAIKey      = "openai_api_key"
+	KeyVisibility     = "visibility"
+	KeyDefaultBranch  = "default_branch"
+	KeyRemote         = "remote"
+	KeyTimeout        = "timeout"
+	KeyAIModel        = "ai_model"
+	KeyUserName       = "user_name"
+	KeyUserEmail      = "user_email"
+	KeyOwner          = "owner"
+	KeyGitHubHost     = "github_host"
+	KeyIgnore         = "ignore"
+	KeySecretsAllow   = "secrets_allowlist"
+	KeyAIProvider     = "ai_provider"
+	KeyAIBaseURL      = "ai_base_url"
+	KeyAnthropicKey   = "anthropic_api_key"
+	KeyAzureKey       = "azure_openai_api_key"
+	KeyAzureVersion   = "azure_api_version"
+	KeyAIDiffTokens   = "ai_max_diff_tokens"
+	KeyAIDiffMode     = "ai_diff_mode"
+	KeyCommitFormat   = "commit_format"
+	KeyCommitTypes    = "commit_types"
+	KeyCommitScopes   = "commit_scopes"
+	KeyCommitStyle    = "commit_style"
+	KeyCommitTemplate = "commit_template"
+	KeyAICandidates   = "ai_candidates"
+	KeyCacheTTL       = "cache_ttl"
+)
+
+// Sources a value can come from, lowest precedence first
+const (
+	SourceDefault = "default"
+	SourceGlobal  = "global file"
+	SourceRepo    = "repo file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+const (
+	DefaultRemote       = "origin"
+	DefaultBranch       = "main"
+	DefaultTimeout      = 120 * time.Second
+	DefaultHost         = "github.com"
+	DefaultAIDiffMode   = "truncate"
+	DefaultCommitFormat = "subject"
+	DefaultCommitStyle  = "auto"
+	// DefaultCacheTTL is how long repository info is reused without
+	// asking GitHub
+	DefaultCacheTTL = time.Hour
+	// MaxAICandidates bounds the parallel requests for candidate messages
+	MaxAICandidates = 5
+)
+
+// Keys lists every supported config key in display order
+var Keys = []string{
+	KeyGitHubToken,
+	KeyGitHubUsername,
+	KeyOpenAIKey,
+	KeyAnthropicKey,
+	KeyAzureKey,
+	KeyVisibility,
+	KeyDefaultBranch,
+	KeyRemote,
+	KeyTimeout,
+	KeyCacheTTL,
+	KeyAIProvider,
+	KeyAIModel,
+	KeyAIBaseURL,
+	KeyAzureVersion,
+	KeyAIDiffTokens,
+	KeyAIDiffMode,
+	KeyAICandidates,
+	KeyCommitFormat,
+	KeyCommitTypes,
+	KeyCommitScopes,
+	KeyCommitStyle,
+	KeyCommitTemplate,
+	KeyUserName,
+	KeyUserEmail,
+	KeyOwner,
+	KeyGitHubHost,
+	KeyIgnore,
+	KeySecretsAllow,
+}
+
+var secretKeys = map[string]bool{
+	KeyGitHubToken:  true,
+	KeyOpenAIKey:    true,
+	KeyAnthropicKey: true,
+	KeyAzureKey:     true,
+}
+
+// repoForbiddenKeys may not come from a per-repo file: secrets because
+// those files are usually committed, and the AI endpoint and GitHub host
+// because a cloned repository could otherwise send its diffs or your
+// tokens anywhere
+var repoForbiddenKeys = map[string]bool{
+	KeyGitHubToken:  true,
+	KeyOpenAIKey:    true,
+	KeyAnthropicKey: true,
+	KeyAzureKey:     true,
+	KeyAIBaseURL:    true,
+	KeyGitHubHost:   true,
+}
+
+// repoPathKeys name files that a per-repo file may only point inside the
+// repository, so a cloned repository cannot have ~/.aws/credentials read
+// into a prompt
+var repoPathKeys = map[string]bool{
+	KeyCommitTemplate: true,
+	KeySecretsAllow:   true,
+}
+
+type Config struct {
+	GitHubToken    string
+	GitHubUsername string
+	OpenAIKey      string
+	// Visibility is public, private, internal, or empty when unset; it is
+	// only applied when creating a repository
+	Visibility    string
+	DefaultBranch string
+	Remote        string
+	Timeout       time.Duration
+	// CacheTTL is how long cached repository info is trusted; zero
+	// disables the cache
+	CacheTTL time.Duration
+	// AIProvider selects the commit message backend; AIModel and AIBaseURL
+	// default per provider when empty
+	AIProvider      string
+	AIModel         string
+	AIBaseURL       string
+	AnthropicKey    string
+	AzureKey        string
+	AzureAPIVersion string
+	// AIDiffTokens caps the diff sent to the model; larger diffs are
+	// truncated or, with AIDiffMode "map-reduce", summarized per file
+	AIDiffTokens int
+	AIDiffMode   string
+	// AICandidates is how many commit messages to generate and choose from
+	AICandidates int
+	// CommitFormat is "subject" for a one-line generated message or "full"
+	// for subject, body and footers
+	CommitFormat string
+	// CommitTypes and CommitScopes restrict generated Conventional Commits
+	// subjects; empty types means the standard set, empty scopes any scope
+	CommitTypes  []string
+	CommitScopes []string
+	// CommitStyle is "auto" to follow the repository's history,
+	// "conventional" or "free-form"
+	CommitStyle string
+	// CommitTemplate is a text/template file for the commit prompt;
+	// relative paths are resolved against the repo root, and a per-repo
+	// file may only use those
+	CommitTemplate string
+	// UserName and UserEmail override the commit identity for the repo
+	UserName  string
+	UserEmail string
+	// Owner is the user or organization that owns new repositories
+	Owner string
+	// GitHubHost is the host for new remotes, github.com or an Enterprise
+	// Server hostname
+	GitHubHost string
+	// Ignore lists globs, relative to the repo root, that are never staged
+	Ignore []string
+	// SecretsAllowlist is the secret scanner allowlist file; relative paths
+	// are resolved against the repo root, and a per-repo file may only use
+	// those
+	SecretsAllowlist string
+
+	// Files lists the config files that were read, in load order
+	Files []string
+	// Sources records which layer supplied each key
+	Sources map[string]string
+}
+
+// Default returns a config populated with built-in defaults only
+func Default() *Config {
+	cfg := &Config{Sources: make(map[string]string)}
+	cfg.mustSet(KeyDefaultBranch, DefaultBranch)
+	cfg.mustSet(KeyRemote, DefaultRemote)
+	cfg.mustSet(KeyTimeout, DefaultTimeout.String())
+	cfg.mustSet(KeyCacheTTL, DefaultCacheTTL.String())
+	cfg.mustSet(KeyAIProvider, ai.ProviderOpenAI)
+	cfg.mustSet(KeyAIDiffTokens, strconv.Itoa(ai.DefaultMaxDiffTokens))
+	cfg.mustSet(KeyAIDiffMode, DefaultAIDiffMode)
+	cfg.mustSet(KeyAICandidates, "1")
+	cfg.mustSet(KeyCommitFormat, DefaultCommitFormat)
+	cfg.mustSet(KeyCommitStyle, DefaultCommitStyle)
+	cfg.mustSet(KeyGitHubHost, DefaultHost)
+	return cfg
+}
+
+func (c *Config) mustSet(key, value string) {
+	if err := c.Set(key, value, SourceDefault); err != nil {
+		panic(err)
+	}
+}
+
+// Set parses value into the field for key and records where it came from
+func (c *Config) Set(key, value, source string) error {
+	value = strings.TrimSpace(value)
+	switch key {
+	case KeyGitHubToken:
+		c.GitHubToken = value
+	case KeyGitHubUsername:
+		c.GitHubUsername = value
+	case KeyOpenAIKey:
+		c.OpenAIKey = value
+	case KeyVisibility:
+		v := strings.ToLower(value)
+		if v != "" && v != "public" && v != "private" && v != "internal" {
+			return fmt.Errorf("invalid %s %q (want public, private or internal)", key, value)
+		}
+		c.Visibility = v
+	case KeyDefaultBranch:
+		c.DefaultBranch = value
+	case KeyRemote:
+		c.Remote = value
+	case KeyTimeout:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid %s %q: must be positive", key, value)
+		}
+		c.Timeout = d
+	case KeyCacheTTL:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("invalid %s %q: must not be negative", key, value)
+		}
+		c.CacheTTL = d
+	case KeyAIModel:
+		c.AIModel = value
+	case KeyAIProvider:
+		v := strings.ToLower(value)
+		if !slices.Contains(ai.Providers, v) {
+			return fmt.Errorf("invalid %s %q (want one of %s)", key, value, strings.Join(ai.Providers, ", "))
+		}
+		c.AIProvider = v
+	case KeyAIBaseURL:
+		c.AIBaseURL = value
+	case KeyAnthropicKey:
+		c.AnthropicKey = value
+	case KeyAzureKey:
+		c.AzureKey = value
+	case KeyAzureVersion:
+		c.AzureAPIVersion = value
+	case KeyAIDiffTokens:
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid %s %q: must be a positive number", key, value)
+		}
+		c.AIDiffTokens = n
+	case KeyAIDiffMode:
+		v := strings.ToLower(value)
+		if v != "truncate" && v != "map-reduce" {
+			return fmt.Errorf("invalid %s %q (want truncate or map-reduce)", key, value)
+		}
+		c.AIDiffMode = v
+	case KeyAICandidates:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxAICandidates {
+			return fmt.Errorf("invalid %s %q: must be between 1 and %d", key, value, MaxAICandidates)
+		}
+		c.AICandidates = n
+	case KeyCommitFormat:
+		v := strings.ToLower(value)
+		if v != "subject" && v != "full" {
+			return fmt.Errorf("invalid %s %q (want subject or full)", key, value)
+		}
+		c.CommitFormat = v
+	case KeyCommitTypes:
+		c.CommitTypes = splitList(value)
+	case KeyCommitScopes:
+		c.CommitScopes = splitList(value)
+	case KeyCommitStyle:
+		v := strings.ToLower(value)
+		if v != "auto" && v != "conventional" && v != "free-form" {
+			return fmt.Errorf("invalid %s %q (want auto, conventional or free-form)", key, value)
+		}
+		c.CommitStyle = v
+	case KeyCommitTemplate:
+		c.CommitTemplate = value
+	case KeyUserName:
+		c.UserName = value
+	case KeyUserEmail:
+		c.UserEmail = value
+	case KeyOwner:
+		c.Owner = value
+	case KeyGitHubHost:
+		c.GitHubHost = strings.ToLower(value)
+	case KeyIgnore:
+		c.Ignore = splitList(value)
+	case KeySecretsAllow:
+		c.SecretsAllowlist = value
+	default:
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	c.Sources[key] = source
+	return nil
+}
+
+// splitList parses a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Get returns the string form of the value for key
+func (c *Config) Get(key string) string {
+	switch key {
+	case KeyGitHubToken:
+		return c.GitHubToken
+	case KeyGitHubUsername:
+		return c.GitHubUsername
+	case KeyOpenAIKey:
+		return c.OpenAIKey
+	case KeyVisibility:
+		return c.Visibility
+	case KeyDefaultBranch:
+		return c.DefaultBranch
+	case KeyRemote:
+		return c.Remote
+	case KeyTimeout:
+		return c.Timeout.String()
+	case KeyCacheTTL:
+		return c.CacheTTL.String()
+	case KeyAIModel:
+		return c.AIModel
+	case KeyAIProvider:
+		return c.AIProvider
+	case KeyAIBaseURL:
+		return c.AIBaseURL
+	case KeyAnthropicKey:
+		return c.AnthropicKey
+	case KeyAzureKey:
+		return c.AzureKey
+	case KeyAzureVersion:
+		return c.AzureAPIVersion
+	case KeyAIDiffTokens:
+		return strconv.Itoa(c.AIDiffTokens)
+	case KeyAIDiffMode:
+		return c.AIDiffMode
+	case KeyAICandidates:
+		return strconv.Itoa(c.AICandidates)
+	case KeyCommitFormat:
+		return c.CommitFormat
+	case KeyCommitTypes:
+		return strings.Join(c.CommitTypes, ",")
+	case KeyCommitScopes:
+		return strings.Join(c.CommitScopes, ",")
+	case KeyCommitStyle:
+		return c.CommitStyle
+	case KeyCommitTemplate:
+		return c.CommitTemplate
+	case KeyUserName:
+		return c.UserName
+	case KeyUserEmail:
+		return c.UserEmail
+	case KeyOwner:
+		return c.Owner
+	case KeyGitHubHost:
+		return c.GitHubHost
+	case KeyIgnore:
+		return strings.Join(c.Ignore, ",")
+	case KeySecretsAllow:
+		return c.SecretsAllowlist
+	}
+	return ""
+}
+
+// Masked returns the value for key, hiding all but a short prefix of secrets
+func (c *Config) Masked(key string) string {
+	value := c.Get(key)
+	if !secretKeys[key] || value == "" {
+		return value
+	}
+	if len(value) <= 8 {
+		return "****"
+	}
+	return value[:4] + "****"
+}
+
+// Capability is a feature an invocation uses, which may need credentials
+type Capability string
+
+const (
+	CapGitHub        Capability = "GitHub access"
+	CapAICommit      Capability = "AI commit messages"
+	CapAIPullRequest Capability = "AI pull request descriptions"
+	// CapGitHubAPI is read-only API use, which needs the token but not
+	// the username git pushes with
+	CapGitHubAPI Capability = "GitHub API access"
+)
+
+// requiredKeys returns the config keys that must be set to use capability
+func (c *Config) requiredKeys(capability Capability) []string {
+	switch capability {
+	case CapGitHub:
+		return []string{KeyGitHubToken, KeyGitHubUsername}
+	case CapGitHubAPI:
+		return []string{KeyGitHubToken}
+	case CapAICommit, CapAIPullRequest:
+		switch c.AIProvider {
+		case ai.ProviderAnthropic:
+			return []string{KeyAnthropicKey}
+		case ai.ProviderAzure:
+			return []string{KeyAzureKey, KeyAIBaseURL, KeyAIModel}
+		case ai.ProviderCompatible:
+			// Self-hosted servers usually need no key
+			return []string{KeyAIBaseURL, KeyAIModel}
+		}
+		return []string{KeyOpenAIKey}
+	}
+	return nil
+}
+
+// TrustsHost reports whether the GitHub token may be sent to host. Only
+// the configured github_host qualifies, so a remote pointing anywhere
+// else never receives it.
+func (c *Config) TrustsHost(host string) bool {
+	return host != "" && strings.EqualFold(host, c.GitHubHost)
+}
+
+// AIAPIKey returns the API key for the selected AI provider. Compatible
+// servers that want a key read it from openai_api_key.
+func (c *Config) AIAPIKey() string {
+	switch c.AIProvider {
+	case ai.ProviderAnthropic:
+		return c.AnthropicKey
+	case ai.ProviderAzure:
+		return c.AzureKey
+	}
+	return c.OpenAIKey
+}
+
+// MissingError reports every credential an invocation needs but lacks
+type MissingError struct {
+	Missing []MissingKey
+}
+
+type MissingKey struct {
+	Key        string
+	Capability Capability
+}
+
+func (e *MissingError) Error() string {
+	var b strings.Builder
+	b.WriteString("missing required configuration:")
+	for _, m := range e.Missing {
+		fmt.Fprintf(&b, "\n  - %s (or %s in a config file), needed for %s", EnvName(m.Key), m.Key, m.Capability)
+	}
+	return b.String()
+}
+
+// Require checks that everything needed by the given capabilities is set,
+// listing all missing values at once rather than stopping at the first
+func (c *Config) Require(capabilities ...Capability) error {
+	var missing []MissingKey
+	seen := make(map[string]bool)
+	for _, capability := range capabilities {
+		for _, key := range c.requiredKeys(capability) {
+			if seen[key] || c.Get(key) != "" {
+				continue
+			}
+			seen[key] = true
+			missing = append(missing, MissingKey{Key: key, Capability: capability})
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingError{Missing: missing}
+	}
+	return nil
+}
+
+// Load resolves the config from every layer in precedence order:
+// built-in defaults, the global file, the per-repo .ghquick.yaml, then
+// environment variables. Flags are applied by the caller on top.
+//
+// path overrides the global file location; dir is the working directory
+// used to locate the per-repo file.
+func Load(path, dir string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultGlobalPath()
+	}
+	if path != "" {
+		if err := cfg.loadFile(path, SourceGlobal, explicit); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir != "" {
+		repoPath := RepoPath(dir)
+		if repoPath != path {
+			if err := cfg.loadFile(repoPath, SourceRepo, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -1,41 +1,48 @@
 package config
 
 import (
-	"errors"
+	"fmt"
 	"os"
+	"strings"
 )
 
 const (
 	EnvGitHubToken    = "GITHUB_TOKEN"
 	EnvGitHubUsername = "GITHUB_USERNAME"
 	EnvOpenAIKey      = "OPENAI_API_KEY"
+	EnvAnthropicKey   = "ANTHROPIC_API_KEY"
+	EnvAzureKey       = "AZURE_OPENAI_API_KEY"
 )
 
-type Config struct {
-	GitHubToken    string
-	GitHubUsername string
-	OpenAIKey      string
+// envNames maps config keys to the conventional variables that hold them.
+// Every other key is read from GHQUICK_<KEY>.
+var envNames = map[string]string{
+	KeyGitHubToken:    EnvGitHubToken,
+	KeyGitHubUsername: EnvGitHubUsername,
+	KeyOpenAIKey:      EnvOpenAIKey,
+	KeyAnthropicKey:   EnvAnthropicKey,
+	KeyAzureKey:       EnvAzureKey,
 }
 
-// LoadFromEnv loads configuration from environment variables
-func LoadFromEnv() (*Config, error) {
-	githubToken := os.Getenv(EnvGitHubToken)
-	githubUsername := os.Getenv(EnvGitHubUsername)
-	openAIKey := os.Getenv(EnvOpenAIKey)
-
-	if githubToken == "" {
-		return nil, errors.New("GITHUB_TOKEN environment variable is required")
-	}
-	if githubUsername == "" {
-		return nil, errors.New("GITHUB_USERNAME environment variable is required")
-	}
-	if openAIKey == "" {
-		return nil, errors.New("OPENAI_API_KEY environment variable is required")
+// EnvName returns the environment variable that sets key
+func EnvName(key string) string {
+	if name, ok := envNames[key]; ok {
+		return name
 	}
+	return "GHQUICK_" + strings.ToUpper(key)
+}
 
-	return &Config{
-		GitHubToken:    githubToken,
-		GitHubUsername: githubUsername,
-		OpenAIKey:      openAIKey,
-	}, nil
+// loadEnv applies environment variables on top of the current values
+func (c *Config) loadEnv() error {
+	for _, key := range Keys {
+		name := EnvName(key)
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := c.Set(key, value, SourceEnv); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
 }
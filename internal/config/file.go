@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of both the global and per-repo config files
+const FileName = ".ghquick.yaml"
+
+// DefaultGlobalPath returns $HOME/.ghquick.yaml, or "" if there is no home
+func DefaultGlobalPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, FileName)
+}
+
+// RepoPath returns the per-repo config path for dir, which lives at the
+// root of the enclosing git work tree (or in dir itself outside a repo)
+func RepoPath(dir string) string {
+	return filepath.Join(findRepoRoot(dir), FileName)
+}
+
+func findRepoRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+func (c *Config) loadFile(path, source string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	// Apply keys in a stable order so errors are deterministic
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if source == SourceRepo && repoForbiddenKeys[key] {
+			return fmt.Errorf("config file %s: %s must not be set in a per-repo config file", path, key)
+		}
+		if source == SourceRepo && repoPathKeys[key] && !localPath(scalarString(values[key])) {
+			return fmt.Errorf("config file %s: %s must be a relative path inside the repository", path, key)
+		}
+		if err := c.Set(key, scalarString(values[key]), source); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	c.Files = append(c.Files, path)
+	return nil
+}
+
+// localPath reports whether p is empty or a relative path that stays
+// inside the directory it is resolved against
+func localPath(p string) bool {
+	return p == "" || filepath.IsLocal(p)
+}
+
+// scalarString flattens a YAML value to the string form accepted by Set;
+// sequences become comma-separated lists
+func scalarString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = scalarString(item)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrDetachedHead is returned when HEAD does not point at a branch
+var ErrDetachedHead = errors.New("HEAD is detached")
+
+// PushTarget describes where a push sends which ref
+type PushTarget struct {
+	Remote string
+	// LocalRef is the local branch name, or "HEAD" when detached
+	LocalRef string
+	// Branch is the branch on the remote being updated
+	Branch string
+	// Detached is set when pushing a detached HEAD
+	Detached bool
+}
+
+func (t *PushTarget) String() string {
+	return fmt.Sprintf("%s/%s", t.Remote, t.Branch)
+}
+
+func (o *Operations) output(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = o.workingDir
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// exitCode returns the exit status of a failed command, or -1
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// CurrentBranch returns the branch HEAD points at, which may be unborn in
+// a repository with no commits yet
+func (o *Operations) CurrentBranch(ctx context.Context) (string, error) {
+	branch, err := o.output(ctx, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		if exitCode(err) == 1 {
+			return "", ErrDetachedHead
+		}
+		return "", fmt.Errorf("failed to read current branch: %w", err)
+	}
+	return branch, nil
+}
+
+// Upstream returns the remote and remote branch that branch tracks, or
+// empty strings if it has no upstream configured
+func (o *Operations) Upstream(ctx context.Context, branch string) (remote, remoteBranch string) {
+	remote, err := o.output(ctx, "config", "--get", fmt.Sprintf("branch.%s.remote", branch))
+	if err != nil || remote == "" || remote == "." {
+		return "", ""
+	}
+	merge, err := o.output(ctx, "config", "--get", fmt.Sprintf("branch.%s.merge", branch))
+	if err != nil || merge == "" {
+		return "", ""
+	}
+	return remote, strings.TrimPrefix(merge, "refs/heads/")
+}
+
+// hasCommits reports whether HEAD resolves to a commit
+func (o *Operations) hasCommits(ctx context.Context) bool {
+	_, err := o.output(ctx, "rev-parse", "--verify", "--quiet", "HEAD")
+	return err == nil
+}
+
+// ensureInitialBranch points an unborn HEAD at branch, so the first commit
+// in a fresh repository lands on the same branch GitHub uses as default
+func (o *Operations) ensureInitialBranch(ctx context.Context, branch string) error {
+	if branch == "" || o.hasCommits(ctx) {
+		return nil
+	}
+	current, err := o.CurrentBranch(ctx)
+	if err != nil || current == branch {
+		return err
+	}
+	o.logger.Step("Using %s as initial branch...", branch)
+	if err := o.runCommand(ctx, "git", "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+		return fmt.Errorf("failed to set initial branch: %w", err)
+	}
+	return nil
+}
+
+// ResolvePushTarget works out what to push where. Explicit remote and
+// branch win; otherwise the current branch's upstream is used, then the
+// current branch name on defaultRemote. A detached HEAD can only be pushed
+// with an explicit branch.
+func (o *Operations) ResolvePushTarget(ctx context.Context, remote, branch, defaultRemote string) (*PushTarget, error) {
+	target := &PushTarget{Remote: remote, Branch: branch}
+
+	current, err := o.CurrentBranch(ctx)
+	switch {
+	case errors.Is(err, ErrDetachedHead):
+		if branch == "" {
+			return nil, fmt.Errorf("%w: check out a branch or pass --branch to choose where to push", ErrDetachedHead)
+		}
+		target.LocalRef = "HEAD"
+		target.Detached = true
+	case err != nil:
+		return nil, err
+	default:
+		target.LocalRef = current
+		upstreamRemote, upstreamBranch := o.Upstream(ctx, current)
+		if target.Remote == "" {
+			target.Remote = upstreamRemote
+		}
+		if target.Branch == "" && upstreamRemote != "" && target.Remote == upstreamRemote {
+			target.Branch = upstreamBranch
+		}
+	}
+
+	if target.Remote == "" {
+		target.Remote = defaultRemote
+	}
+	if target.Branch == "" {
+		target.Branch = current
+	}
+
+	o.logger.Debug("Push target: %s -> %s", target.LocalRef, target)
+	return target, nil
+}
@@ -0,0 +1,116 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// credentialPattern matches user:password@ in URLs embedded in git output
+var credentialPattern = regexp.MustCompile(`(\w+://[^/\s:@]+):[^@\s]+@`)
+
+// redactCredentials masks passwords or tokens embedded in URLs so they
+// never reach the terminal or debug logs
+func redactCredentials(s string) string {
+	return credentialPattern.ReplaceAllString(s, "$1:****@")
+}
+
+// SetCredentialHelper makes every git invocation authenticate through
+// helper (a git credential.helper value) instead of any configured helper
+func (o *Operations) SetCredentialHelper(helper string) {
+	o.credentialHelper = helper
+}
+
+func (o *Operations) gitArgs(args []string) []string {
+	if o.credentialHelper == "" {
+		return args
+	}
+	// The empty value resets helpers inherited from user config so that
+	// stale keychain entries can't shadow the configured token
+	prefix := []string{"-c", "credential.helper=", "-c", "credential.helper=" + o.credentialHelper}
+	return append(prefix, args...)
+}
+
+// ScrubRemoteCredentials rewrites any remote URL that embeds credentials
+// (as older ghquick versions did) into its token-free form
+func (o *Operations) ScrubRemoteCredentials(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "git", "config", "--local", "--get-regexp", `^remote\..*\.(url|pushurl)$`)
+	cmd.Dir = o.workingDir
+	output, err := cmd.Output()
+	if err != nil {
+		// Exit status 1 means no remotes are configured
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil
+		}
+		return fmt.Errorf("failed to list remotes: %w", err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		key, rawURL, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		clean, changed := stripCredentials(rawURL)
+		if !changed {
+			continue
+		}
+		o.logger.Warning("Removing embedded credentials from %s", key)
+		if err := o.runCommand(ctx, "git", "config", "--local", key, clean); err != nil {
+			return fmt.Errorf("failed to scrub credentials from %s: %w", key, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// stripCredentials drops the userinfo password from an HTTP(S) URL
+func stripCredentials(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User == nil {
+		return rawURL, false
+	}
+	if _, hasPassword := u.User.Password(); !hasPassword {
+		return rawURL, false
+	}
+	u.User = nil
+	return u.String(), true
+}
+
+// Credential is a set of attributes exchanged over the git credential
+// helper protocol (see gitcredentials(7))
+type Credential map[string]string
+
+// ReadCredential parses key=value lines up to a blank line or EOF
+func ReadCredential(r io.Reader) (Credential, error) {
+	cred := make(Credential)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed credential line %q", line)
+		}
+		cred[key] = value
+	}
+	return cred, scanner.Err()
+}
+
+// Write emits the credential in the git credential helper format
+func (c Credential) Write(w io.Writer) error {
+	for _, key := range []string{"protocol", "host", "username", "password"} {
+		if value, ok := c[key]; ok {
+			if _, err := fmt.Fprintf(w, "%s=%s\n", key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editHelp is appended to messages opened in the editor, like git commit
+const editHelp = `
+# Edit the commit message above. Lines starting with '#' are ignored,
+# and an empty message aborts the commit.
+`
+
+// Editor returns the editor command git would use, honoring GIT_EDITOR,
+// core.editor, VISUAL and EDITOR in that order
+func (o *Operations) Editor(ctx context.Context) (string, error) {
+	editor, err := o.output(ctx, "var", "GIT_EDITOR")
+	if err != nil || editor == "" {
+		return "", fmt.Errorf("failed to find an editor (set $EDITOR): %w", err)
+	}
+	return editor, nil
+}
+
+// EditMessage opens message in the user's editor and returns the result
+// with comment lines removed. It waits for the editor however long it
+// takes, so it is deliberately not bound to ctx's deadline.
+func (o *Operations) EditMessage(ctx context.Context, message string) (string, error) {
+	editor, err := o.Editor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "ghquick-COMMIT_EDITMSG-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create message file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(message + "\n" + editHelp)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	// Editors are shell snippets ("code --wait"), run them the way git does
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, f.Name())
+	cmd.Dir = o.workingDir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %w", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
@@ -0,0 +1,59 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FetchBranch updates the remote-tracking ref for remote/branch
+func (o *Operations) FetchBranch(ctx context.Context, remote, branch string) error {
+	if err := o.runCommand(ctx, "git", "fetch", remote, branch); err != nil {
+		return fmt.Errorf("failed to fetch %s/%s: %w", remote, branch, err)
+	}
+	return nil
+}
+
+// CommitLog returns the messages of commits reachable from head but not
+// base, oldest first, separated by blank lines
+func (o *Operations) CommitLog(ctx context.Context, base, head string) (string, error) {
+	log, err := o.output(ctx, "log", "--reverse", "--no-merges", "--format=%s%n%n%b", base+".."+head)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commits %s..%s: %w", base, head, err)
+	}
+	return log, nil
+}
+
+// RangeDiff returns the changes head introduces since it diverged from base
+func (o *Operations) RangeDiff(ctx context.Context, base, head string) (string, error) {
+	diff, err := o.output(ctx, "diff", base+"..."+head)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s...%s: %w", base, head, err)
+	}
+	return diff, nil
+}
+
+// TopLevel returns the root directory of the work tree
+func (o *Operations) TopLevel(ctx context.Context) (string, error) {
+	dir, err := o.output(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %w", err)
+	}
+	return dir, nil
+}
+
+// RecentSubjects returns the subjects of up to n of the latest non-merge
+// commits on HEAD, newest first. A repository without commits has none.
+func (o *Operations) RecentSubjects(ctx context.Context, n int) ([]string, error) {
+	if !o.hasCommits(ctx) {
+		return nil, nil
+	}
+	log, err := o.output(ctx, "log", "--no-merges", fmt.Sprintf("--max-count=%d", n), "--format=%s")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit history: %w", err)
+	}
+	if log == "" {
+		return nil, nil
+	}
+	return strings.Split(log, "\n"), nil
+}
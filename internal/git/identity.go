@@ -0,0 +1,73 @@
+package git
+
+import (
+	"context"
+	"fmt"
+)
+
+// Identity is the author name and email git records on commits
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// CurrentIdentity returns the effective identity, as resolved by git from
+// repo, global and system config
+func (o *Operations) CurrentIdentity(ctx context.Context) Identity {
+	name, _ := o.output(ctx, "config", "user.name")
+	email, _ := o.output(ctx, "config", "user.email")
+	return Identity{Name: name, Email: email}
+}
+
+// EnsureIdentity makes sure commits have an author without touching the
+// user's global config. Fields set in override are always written to the
+// repo-local config; anything still missing after that is filled from
+// fallback, which is only called when needed.
+func (o *Operations) EnsureIdentity(ctx context.Context, override Identity, fallback func(context.Context) (Identity, error)) error {
+	o.logger.Step("Checking git identity...")
+	current := o.CurrentIdentity(ctx)
+
+	if override.Name != "" && override.Name != current.Name {
+		if err := o.setLocalConfig(ctx, "user.name", override.Name); err != nil {
+			return err
+		}
+		current.Name = override.Name
+	}
+	if override.Email != "" && override.Email != current.Email {
+		if err := o.setLocalConfig(ctx, "user.email", override.Email); err != nil {
+			return err
+		}
+		current.Email = override.Email
+	}
+
+	if current.Name != "" && current.Email != "" {
+		o.logger.Debug("Committing as %s <%s>", current.Name, current.Email)
+		return nil
+	}
+
+	derived, err := fallback(ctx)
+	if err != nil {
+		o.logger.Error("Failed to determine git identity")
+		return fmt.Errorf("git identity is not configured and could not be derived: %w", err)
+	}
+	if current.Name == "" {
+		if err := o.setLocalConfig(ctx, "user.name", derived.Name); err != nil {
+			return err
+		}
+	}
+	if current.Email == "" {
+		if err := o.setLocalConfig(ctx, "user.email", derived.Email); err != nil {
+			return err
+		}
+	}
+	o.logger.Success("Git identity configured for this repository")
+	return nil
+}
+
+func (o *Operations) setLocalConfig(ctx context.Context, key, value string) error {
+	if err := o.runCommand(ctx, "git", "config", "--local", key, value); err != nil {
+		o.logger.Error("Failed to set %s", key)
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	return nil
+}
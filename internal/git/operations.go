@@ -12,8 +12,9 @@ import (
 )
 
 type Operations struct {
-	workingDir string
-	logger     *log.Logger
+	workingDir       string
+	logger           *log.Logger
+	credentialHelper string
 }
 
 func NewOperations(workingDir string, debug bool) *Operations {
@@ -50,29 +51,30 @@ func (o *Operations) runCommand(ctx context.Context, name string, args ...string
 		}
 	}
 
-	o.logger.Command(name, args...)
-	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Dir = o.workingDir
-	if output, err := cmd.CombinedOutput(); err != nil {
-		o.logger.Debug("Command output: %s", string(output))
-		return fmt.Errorf("%w: %s", err, string(output))
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = redactCredentials(arg)
 	}
-	return nil
-}
+	o.logger.Command(name, redacted...)
 
-func (o *Operations) configureGitUser(ctx context.Context) error {
-	o.logger.Step("Configuring git user...")
-	cmd := exec.CommandContext(ctx, "git", "config", "--global", "user.name", os.Getenv("GITHUB_USERNAME"))
+	if name == "git" {
+		args = o.gitArgs(args)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Dir = o.workingDir
-	if err := cmd.Run(); err != nil {
-		o.logger.Error("Failed to set git username")
-		return fmt.Errorf("failed to set git user.name: %w", err)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		out := redactCredentials(string(output))
+		o.logger.Debug("Command output: %s", out)
+		return fmt.Errorf("%w: %s", err, out)
 	}
-	o.logger.Success("Git user configured")
 	return nil
 }
 
-func (o *Operations) EnsureGitSetup(ctx context.Context, repoName string) error {
+// EnsureGitSetup initializes the repository if needed and removes any
+// credentials older versions stored in remote URLs; authentication goes
+// through the credential helper instead. initialBranch names the branch
+// used when the repository has no commits yet.
+func (o *Operations) EnsureGitSetup(ctx context.Context, initialBranch string) error {
 	// Check if .git directory exists
 	gitDir := filepath.Join(o.workingDir, ".git")
 	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
@@ -86,37 +88,13 @@ func (o *Operations) EnsureGitSetup(ctx context.Context, repoName string) error
 		o.logger.Info("Git repository already initialized")
 	}
 
-	// Configure git user
-	if err := o.configureGitUser(ctx); err != nil {
+	if err := o.ensureInitialBranch(ctx, initialBranch); err != nil {
 		return err
 	}
 
-	// Check if remote origin exists
-	o.logger.Step("Checking remote configuration...")
-	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
-	cmd.Dir = o.workingDir
-	if err := cmd.Run(); err != nil {
-		// Add remote origin with authentication
-		token := os.Getenv("GITHUB_TOKEN")
-		username := os.Getenv("GITHUB_USERNAME")
-		remoteURL := fmt.Sprintf("https://%s:%s@github.com/%s/%s.git", username, token, username, repoName)
-		o.logger.Step("Adding remote origin...")
-		if err := o.runCommand(ctx, "git", "remote", "add", "origin", remoteURL); err != nil {
-			o.logger.Error("Failed to add remote origin")
-			return fmt.Errorf("failed to add remote origin: %w", err)
-		}
-		o.logger.Success("Remote origin added")
-	} else {
-		// Update existing remote to use authentication
-		token := os.Getenv("GITHUB_TOKEN")
-		username := os.Getenv("GITHUB_USERNAME")
-		remoteURL := fmt.Sprintf("https://%s:%s@github.com/%s/%s.git", username, token, username, repoName)
-		o.logger.Step("Updating remote origin...")
-		if err := o.runCommand(ctx, "git", "remote", "set-url", "origin", remoteURL); err != nil {
-			o.logger.Error("Failed to update remote origin")
-			return fmt.Errorf("failed to update remote origin: %w", err)
-		}
-		o.logger.Success("Remote origin updated")
+	// Older versions stored the token in the remote URL; remove it
+	if err := o.ScrubRemoteCredentials(ctx); err != nil {
+		return err
 	}
 
 	return nil
@@ -148,36 +126,41 @@ func (o *Operations) GetDiff(ctx context.Context) (string, error) {
 	return string(output), nil
 }
 
-func (o *Operations) StageAll(ctx context.Context) error {
-	o.logger.Step("Staging all changes...")
-
-	// First try git add -A
-	if err := o.runCommand(ctx, "git", "add", "-A"); err != nil {
-		o.logger.Warning("Failed to stage with -A flag, trying alternative method...")
+// Stage stages the changes selected by opts: everything (or just the
+// pathspecs) minus the exclude globs, on top of .gitignore. It returns
+// ErrNoChanges when nothing ends up staged.
+func (o *Operations) Stage(ctx context.Context, opts StageOptions) error {
+	if len(opts.Pathspecs) == 0 {
+		o.logger.Step("Staging all changes...")
+	} else {
+		o.logger.Step("Staging %s...", strings.Join(opts.Pathspecs, ", "))
+	}
 
-		// If that fails, try explicit path
-		if err := o.runCommand(ctx, "git", "add", o.workingDir); err != nil {
-			o.logger.Error("Failed to stage changes")
-			return fmt.Errorf("failed to stage files: %w", err)
-		}
+	args := append([]string{"add", "-A", "--"}, opts.pathspecArgs()...)
+	if err := o.runCommand(ctx, "git", args...); err != nil {
+		o.logger.Error("Failed to stage changes")
+		return fmt.Errorf("failed to stage files: %w", err)
 	}
 
-	// Verify files were staged
-	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain")
-	cmd.Dir = o.workingDir
-	output, err := cmd.Output()
+	return o.RequireStaged(ctx)
+}
+
+// RequireStaged checks that the index has changes to commit, returning
+// ErrNoChanges otherwise
+func (o *Operations) RequireStaged(ctx context.Context) error {
+	staged, err := o.StagedFiles(ctx)
 	if err != nil {
 		o.logger.Error("Failed to check git status")
-		return fmt.Errorf("failed to check git status: %w", err)
+		return err
 	}
 
-	if len(output) == 0 {
+	if len(staged) == 0 {
 		o.logger.Warning("No changes to stage")
-		return fmt.Errorf("no changes to commit")
+		return ErrNoChanges
 	}
 
 	o.logger.Success("Changes staged")
-	o.logger.Debug("Staged files:\n%s", string(output))
+	o.logger.Debug("Staged files:\n%s", strings.Join(staged, "\n"))
 	return nil
 }
 
@@ -191,19 +174,25 @@ func (o *Operations) Commit(ctx context.Context, message string) error {
 	return nil
 }
 
-func (o *Operations) HasRemoteDiffs(ctx context.Context, remote, branch string) (bool, error) {
+// HasRemoteDiffs reports whether the target's local ref has commits the
+// remote branch lacks, treating a missing remote branch as out of date
+func (o *Operations) HasRemoteDiffs(ctx context.Context, target *PushTarget) (bool, error) {
 	o.logger.Step("Checking for unpushed changes...")
 
 	// Fetch latest changes
-	if err := o.runCommand(ctx, "git", "fetch", remote, branch); err != nil {
+	if err := o.runCommand(ctx, "git", "fetch", target.Remote, target.Branch); err != nil {
+		if strings.Contains(err.Error(), "couldn't find remote ref") {
+			o.logger.Debug("Remote branch doesn't exist yet")
+			return true, nil
+		}
 		o.logger.Error("Failed to fetch remote changes")
 		return false, fmt.Errorf("failed to fetch: %w", err)
 	}
 
 	// Check if we have any commits to push
-	cmd := exec.CommandContext(ctx, "git", "rev-list", "HEAD", fmt.Sprintf("^%s/%s", remote, branch), "--count")
+	cmd := exec.CommandContext(ctx, "git", "rev-list", target.LocalRef, fmt.Sprintf("^%s/%s", target.Remote, target.Branch), "--count")
 	cmd.Dir = o.workingDir
-	output, err := cmd.Output()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// If branch doesn't exist yet, we definitely have changes to push
 		if strings.Contains(string(output), "unknown revision") {
@@ -226,18 +215,13 @@ func (o *Operations) HasRemoteDiffs(ctx context.Context, remote, branch string)
 	return hasDiffs, nil
 }
 
-func (o *Operations) Push(ctx context.Context, remote, branch string) error {
-	if remote == "" {
-		remote = "origin"
-	}
-	if branch == "" {
-		branch = "main"
-	}
-
+// Push pushes target's commits, if the remote doesn't have them yet.
+// Failures are returned as *PushError.
+func (o *Operations) Push(ctx context.Context, target *PushTarget) error {
 	// Check if we have any changes to push
-	hasDiffs, err := o.HasRemoteDiffs(ctx, remote, branch)
+	hasDiffs, err := o.HasRemoteDiffs(ctx, target)
 	if err != nil {
-		return err
+		return newPushError(err, target)
 	}
 
 	if !hasDiffs {
@@ -245,10 +229,17 @@ func (o *Operations) Push(ctx context.Context, remote, branch string) error {
 		return nil
 	}
 
-	o.logger.Step("Pushing to %s/%s...", remote, branch)
-	if err := o.runCommand(ctx, "git", "push", "-u", remote, branch); err != nil {
+	args := []string{"push"}
+	if !target.Detached {
+		// Only a real branch can track its upstream
+		args = append(args, "-u")
+	}
+	args = append(args, target.Remote, fmt.Sprintf("%s:refs/heads/%s", target.LocalRef, target.Branch))
+
+	o.logger.Step("Pushing %s to %s...", target.LocalRef, target)
+	if err := o.runCommand(ctx, "git", args...); err != nil {
 		o.logger.Error("Failed to push changes")
-		return fmt.Errorf("failed to push: %w", err)
+		return newPushError(err, target)
 	}
 	o.logger.Success("Changes pushed successfully")
 	return nil
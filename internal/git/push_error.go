@@ -0,0 +1,125 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PushErrorKind says why a push failed
+type PushErrorKind string
+
+const (
+	PushErrUnknown         PushErrorKind = "unknown"
+	PushErrAuth            PushErrorKind = "authentication failed"
+	PushErrNonFastForward  PushErrorKind = "non-fast-forward"
+	PushErrNetwork         PushErrorKind = "network error"
+	PushErrProtectedBranch PushErrorKind = "protected branch"
+	PushErrLargeFile       PushErrorKind = "file too large"
+	PushErrHookDeclined    PushErrorKind = "hook declined"
+)
+
+// PushError is a failed push (or the fetch before it), classified from
+// git's output
+type PushError struct {
+	Kind   PushErrorKind
+	Target *PushTarget
+	// Remote holds the "remote:" lines the server sent, which usually
+	// explain a rejection
+	Remote string
+	Err    error
+}
+
+func (e *PushError) Error() string {
+	if e.Kind == PushErrUnknown {
+		return fmt.Sprintf("failed to push: %v", e.Err)
+	}
+	msg := fmt.Sprintf("failed to push (%s): %s", e.Kind, e.Hint())
+	if e.Remote != "" {
+		msg += "\n" + e.Remote
+	}
+	return msg
+}
+
+func (e *PushError) Unwrap() error {
+	return e.Err
+}
+
+// Transient reports whether the same push might succeed if retried
+func (e *PushError) Transient() bool {
+	return e.Kind == PushErrNetwork
+}
+
+// Hint says what to do about the failure
+func (e *PushError) Hint() string {
+	switch e.Kind {
+	case PushErrAuth:
+		return "GitHub refused the credentials; check that the token is valid, has the repo scope " +
+			"(or Contents write access for fine-grained tokens) and is authorized for your organization's SSO"
+	case PushErrNonFastForward:
+		return fmt.Sprintf("%s has commits you don't have; run 'git pull --rebase %s %s' and push again",
+			e.Target, e.Target.Remote, e.Target.Branch)
+	case PushErrNetwork:
+		return "couldn't reach the remote; check your connection, VPN or proxy settings"
+	case PushErrProtectedBranch:
+		return fmt.Sprintf("%s is protected; push to another branch with --branch and open a pull request with --pr",
+			e.Target.Branch)
+	case PushErrLargeFile:
+		return "a file exceeds GitHub's 100 MB limit; remove it from the commit with 'git reset --soft HEAD~1' " +
+			"or track it with Git LFS"
+	case PushErrHookDeclined:
+		return "a server-side hook rejected the push; see its message below"
+	}
+	return ""
+}
+
+var (
+	// Checked in order, so more specific rejections win over the generic
+	// ones they also match
+	pushErrorPatterns = []struct {
+		kind    PushErrorKind
+		pattern *regexp.Regexp
+	}{
+		{PushErrProtectedBranch, regexp.MustCompile(`protected branch|gh006`)},
+		{PushErrLargeFile, regexp.MustCompile(`gh001|large files detected|exceeds github's file size limit|http 413|entity too large`)},
+		{PushErrAuth, regexp.MustCompile(`authentication failed|could not read username|invalid username or password|` +
+			`permission denied|permission to \S+ denied|terminal prompts disabled|returned error: 40[13]|repository not found|` +
+			`saml sso|bad credentials`)},
+		{PushErrNonFastForward, regexp.MustCompile(`non-fast-forward|fetch first|tip of your current branch is behind|updates were rejected`)},
+		{PushErrHookDeclined, regexp.MustCompile(`hook declined|remote rejected`)},
+		{PushErrNetwork, regexp.MustCompile(`could not resolve host|temporary failure in name resolution|connection timed out|` +
+			`operation timed out|connection refused|connection reset|network is unreachable|no route to host|` +
+			`remote end hung up unexpectedly|early eof|rpc failed|failed to connect|ssl_error|gnutls|tls handshake|` +
+			`returned error: 5\d\d|internal server error|service unavailable|bad gateway`)},
+	}
+	// git output follows the command error on the first line
+	remoteLine = regexp.MustCompile(`(?m)(?:^|: )remote:[ \t]?(.*)$`)
+)
+
+// ClassifyPushError works out why git push or fetch failed from the error,
+// which includes git's output
+func ClassifyPushError(err error) PushErrorKind {
+	output := strings.ToLower(err.Error())
+	for _, p := range pushErrorPatterns {
+		if p.pattern.MatchString(output) {
+			return p.kind
+		}
+	}
+	return PushErrUnknown
+}
+
+// newPushError classifies err from pushing target
+func newPushError(err error, target *PushTarget) *PushError {
+	var lines []string
+	for _, m := range remoteLine.FindAllStringSubmatch(err.Error(), -1) {
+		if line := strings.TrimSpace(m[1]); line != "" {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return &PushError{
+		Kind:   ClassifyPushError(err),
+		Target: target,
+		Remote: strings.Join(lines, "\n"),
+		Err:    err,
+	}
+}
@@ -0,0 +1,218 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// gitError wraps stderr the way runCommand reports a failed git command
+func gitError(status int, stderr string) error {
+	return fmt.Errorf("%w: %s", fmt.Errorf("exit status %d", status), stderr)
+}
+
+func TestClassifyPushError(t *testing.T) {
+	tests := []struct {
+		name      string
+		stderr    string
+		kind      PushErrorKind
+		transient bool
+		remote    string
+	}{
+		{
+			name: "protected branch",
+			stderr: `remote: error: GH006: Protected branch update failed for refs/heads/main.
+remote: error: Changes must be made through a pull request.
+To https://github.com/octo/app.git
+ ! [remote rejected] main -> main (protected branch hook declined)
+error: failed to push some refs to 'https://github.com/octo/app.git'
+`,
+			kind: PushErrProtectedBranch,
+			remote: "  error: GH006: Protected branch update failed for refs/heads/main.\n" +
+				"  error: Changes must be made through a pull request.",
+		},
+		{
+			name: "pre-receive hook",
+			stderr: `remote: Commit message must reference a ticket
+To ghe.example.com:octo/app.git
+ ! [remote rejected] main -> main (pre-receive hook declined)
+error: failed to push some refs to 'ghe.example.com:octo/app.git'
+`,
+			kind:   PushErrHookDeclined,
+			remote: "  Commit message must reference a ticket",
+		},
+		{
+			name: "push protection",
+			stderr: `remote: error: GH013: Repository rule violations found for refs/heads/main.
+remote: - GITHUB PUSH PROTECTION
+remote:   Push cannot contain secrets
+To https://github.com/octo/app.git
+ ! [remote rejected] main -> main (push declined due to repository rule violations)
+error: failed to push some refs to 'https://github.com/octo/app.git'
+`,
+			kind: PushErrHookDeclined,
+			remote: "  error: GH013: Repository rule violations found for refs/heads/main.\n" +
+				"  - GITHUB PUSH PROTECTION\n" +
+				"  Push cannot contain secrets",
+		},
+		{
+			name: "remote rejected for permissions",
+			stderr: `To https://github.com/octo/app.git
+ ! [remote rejected] main -> main (permission denied)
+error: failed to push some refs to 'https://github.com/octo/app.git'
+`,
+			kind: PushErrAuth,
+		},
+		{
+			name: "large file",
+			stderr: `remote: error: Trace: 8f1c2a
+remote: error: See https://gh.io/lfs for more information.
+remote: error: File data/dump.bin is 120.00 MB; this exceeds GitHub's file size limit of 100.00 MB
+remote: error: GH001: Large files detected. You may want to try Git Large File Storage - https://git-lfs.github.com.
+To https://github.com/octo/app.git
+ ! [remote rejected] main -> main (pre-receive hook declined)
+error: failed to push some refs to 'https://github.com/octo/app.git'
+`,
+			kind: PushErrLargeFile,
+		},
+		{
+			name: "large pack over HTTP",
+			stderr: `error: RPC failed; HTTP 413 curl 22 The requested URL returned error: 413
+send-pack: unexpected disconnect while reading sideband packet
+fatal: the remote end hung up unexpectedly
+`,
+			kind: PushErrLargeFile,
+		},
+		{
+			name: "authentication failed",
+			stderr: `remote: Invalid username or password.
+fatal: Authentication failed for 'https://github.com/octo/app.git/'
+`,
+			kind:   PushErrAuth,
+			remote: "  Invalid username or password.",
+		},
+		{
+			name: "token without access",
+			stderr: `remote: Permission to octo/app.git denied to hubot.
+fatal: unable to access 'https://github.com/octo/app.git/': The requested URL returned error: 403
+`,
+			kind:   PushErrAuth,
+			remote: "  Permission to octo/app.git denied to hubot.",
+		},
+		{
+			name: "ssh key refused",
+			stderr: `git@github.com: Permission denied (publickey).
+fatal: Could not read from remote repository.
+
+Please make sure you have the correct access rights
+and the repository exists.
+`,
+			kind: PushErrAuth,
+		},
+		{
+			name: "no credentials",
+			stderr: `fatal: could not read Username for 'https://github.com': terminal prompts disabled
+`,
+			kind: PushErrAuth,
+		},
+		{
+			name: "repository not found",
+			stderr: `remote: Repository not found.
+fatal: repository 'https://github.com/octo/gone.git/' not found
+`,
+			kind:   PushErrAuth,
+			remote: "  Repository not found.",
+		},
+		{
+			name: "fetch first",
+			stderr: `To https://github.com/octo/app.git
+ ! [rejected]        main -> main (fetch first)
+error: failed to push some refs to 'https://github.com/octo/app.git'
+hint: Updates were rejected because the remote contains work that you do not
+hint: have locally. This is usually caused by another repository pushing to
+hint: the same ref. If you want to integrate the remote changes, use
+hint: 'git pull' before pushing again.
+`,
+			kind: PushErrNonFastForward,
+		},
+		{
+			name: "behind remote",
+			stderr: `To https://github.com/octo/app.git
+ ! [rejected]        main -> main (non-fast-forward)
+error: failed to push some refs to 'https://github.com/octo/app.git'
+hint: Updates were rejected because the tip of your current branch is behind
+hint: its remote counterpart.
+`,
+			kind: PushErrNonFastForward,
+		},
+		{
+			name: "unknown host",
+			stderr: `fatal: unable to access 'https://github.com/octo/app.git/': Could not resolve host: github.com
+`,
+			kind:      PushErrNetwork,
+			transient: true,
+		},
+		{
+			name: "ssh unknown host",
+			stderr: `ssh: Could not resolve hostname github.com: Temporary failure in name resolution
+fatal: Could not read from remote repository.
+`,
+			kind:      PushErrNetwork,
+			transient: true,
+		},
+		{
+			name: "connect timeout",
+			stderr: `fatal: unable to access 'https://github.com/octo/app.git/': Failed to connect to github.com port 443 after 21045 ms: Timed out
+`,
+			kind:      PushErrNetwork,
+			transient: true,
+		},
+		{
+			name: "server error",
+			stderr: `error: RPC failed; HTTP 502 curl 22 The requested URL returned error: 502
+send-pack: unexpected disconnect while reading sideband packet
+fatal: the remote end hung up unexpectedly
+Everything up-to-date
+`,
+			kind:      PushErrNetwork,
+			transient: true,
+		},
+		{
+			name: "dropped connection",
+			stderr: `error: RPC failed; curl 56 GnuTLS recv error (-9): A TLS packet with unexpected length was received.
+fatal: the remote end hung up unexpectedly
+`,
+			kind:      PushErrNetwork,
+			transient: true,
+		},
+		{
+			name: "unknown",
+			stderr: `error: src refspec feature does not match any
+error: failed to push some refs to 'https://github.com/octo/app.git'
+`,
+			kind: PushErrUnknown,
+		},
+	}
+
+	target := &PushTarget{Remote: "origin", Branch: "main", LocalRef: "HEAD"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newPushError(gitError(1, tt.stderr), target)
+			if err.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", err.Kind, tt.kind)
+			}
+			if err.Transient() != tt.transient {
+				t.Errorf("Transient() = %v, want %v", err.Transient(), tt.transient)
+			}
+			if tt.remote != "" && err.Remote != tt.remote {
+				t.Errorf("Remote =\n%s\nwant\n%s", err.Remote, tt.remote)
+			}
+			if !errors.Is(err, err.Err) {
+				t.Errorf("PushError does not unwrap to the git error")
+			}
+			if tt.kind != PushErrUnknown && err.Hint() == "" {
+				t.Errorf("no hint for %q", tt.kind)
+			}
+		})
+	}
+}
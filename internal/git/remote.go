@@ -0,0 +1,132 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RemoteURL is a parsed GitHub-style remote: host/owner/repo
+type RemoteURL struct {
+	Host  string
+	Owner string
+	Repo  string
+	// SSH is set for ssh:// and scp-like remotes, with the login user and
+	// an optional non-default port
+	SSH  bool
+	User string
+	Port string
+}
+
+// ParseRemoteURL understands the HTTPS (https://host/owner/repo.git),
+// ssh:// (ssh://git@host/owner/repo.git) and scp-like
+// (git@host:owner/repo.git) forms of a remote URL
+func ParseRemoteURL(raw string) (*RemoteURL, error) {
+	r := &RemoteURL{}
+	var path string
+	switch {
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote URL %q: %w", redactCredentials(raw), err)
+		}
+		switch u.Scheme {
+		case "https", "http":
+		case "ssh", "git+ssh":
+			r.SSH = true
+			r.User = u.User.Username()
+			r.Port = u.Port()
+		default:
+			return nil, fmt.Errorf("unsupported remote URL scheme %q", u.Scheme)
+		}
+		r.Host, path = u.Hostname(), u.Path
+	case strings.Contains(raw, ":"):
+		// scp-like syntax: [user@]host:path
+		hostPart, p, _ := strings.Cut(raw, ":")
+		r.SSH = true
+		if user, h, ok := strings.Cut(hostPart, "@"); ok {
+			r.User, hostPart = user, h
+		}
+		r.Host, path = hostPart, p
+	default:
+		return nil, fmt.Errorf("unsupported remote URL %q", raw)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, repo, ok := strings.Cut(path, "/")
+	if !ok || r.Host == "" || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("remote URL %q is not of the form host/owner/repo", redactCredentials(raw))
+	}
+	r.Host, r.Owner, r.Repo = strings.ToLower(r.Host), owner, repo
+	return r, nil
+}
+
+func (r *RemoteURL) String() string {
+	return fmt.Sprintf("%s/%s/%s", r.Host, r.Owner, r.Repo)
+}
+
+// Same reports whether both point at the same repository, ignoring case
+// and transport
+func (r *RemoteURL) Same(other *RemoteURL) bool {
+	return strings.EqualFold(r.String(), other.String())
+}
+
+// URL renders the remote in the same form it was parsed from, defaulting
+// to HTTPS
+func (r *RemoteURL) URL() string {
+	if !r.SSH {
+		return fmt.Sprintf("https://%s/%s/%s.git", r.Host, r.Owner, r.Repo)
+	}
+	user := r.User
+	if user == "" {
+		user = "git"
+	}
+	if r.Port != "" {
+		return fmt.Sprintf("ssh://%s@%s:%s/%s/%s.git", user, r.Host, r.Port, r.Owner, r.Repo)
+	}
+	return fmt.Sprintf("%s@%s:%s/%s.git", user, r.Host, r.Owner, r.Repo)
+}
+
+// RemoteInfo parses the URL of remote, returning nil if the remote is not
+// configured or the repository doesn't exist yet
+func (o *Operations) RemoteInfo(ctx context.Context, remote string) (*RemoteURL, error) {
+	raw, err := o.output(ctx, "remote", "get-url", remote)
+	if err != nil || raw == "" {
+		return nil, nil
+	}
+	return ParseRemoteURL(raw)
+}
+
+// EnsureRemote makes remote point at want. A missing remote is added; an
+// existing one is left alone unless fix is set, so forks, SSH and
+// Enterprise remotes are never rewritten behind the user's back.
+func (o *Operations) EnsureRemote(ctx context.Context, remote string, want *RemoteURL, fix bool) error {
+	o.logger.Step("Checking remote configuration...")
+	current, err := o.output(ctx, "remote", "get-url", remote)
+	if err != nil || current == "" {
+		o.logger.Step("Adding remote %s...", remote)
+		if err := o.runCommand(ctx, "git", "remote", "add", remote, want.URL()); err != nil {
+			o.logger.Error("Failed to add remote %s", remote)
+			return fmt.Errorf("failed to add remote %s: %w", remote, err)
+		}
+		o.logger.Success("Remote %s added", remote)
+		return nil
+	}
+
+	if parsed, err := ParseRemoteURL(current); err == nil && parsed.Same(want) {
+		o.logger.Debug("Remote %s already points at %s", remote, want)
+		return nil
+	}
+	if !fix {
+		return nil
+	}
+
+	o.logger.Step("Updating remote %s to %s...", remote, want)
+	if err := o.runCommand(ctx, "git", "remote", "set-url", remote, want.URL()); err != nil {
+		o.logger.Error("Failed to update remote %s", remote)
+		return fmt.Errorf("failed to update remote %s: %w", remote, err)
+	}
+	o.logger.Success("Remote %s updated", remote)
+	return nil
+}
@@ -0,0 +1,255 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AllowSecretMarker on an added line suppresses findings on that line
+const AllowSecretMarker = "ghquick:allow-secret"
+
+// DefaultAllowlistFile is the allowlist read from the repository root
+const DefaultAllowlistFile = ".ghquick-secrets-allow"
+
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var secretRules = []secretRule{
+	{"GitHub token", regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b`)},
+	{"GitHub fine-grained token", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{82}\b`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{"AWS secret access key", regexp.MustCompile(`(?i)aws_?secret_?(access_?)?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{"OpenAI API key", regexp.MustCompile(`\bsk-(proj-)?[A-Za-z0-9_-]{32,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+}
+
+// Entropy is only checked on lines that look like they assign a secret,
+// which keeps hashes in lockfiles and docs from being flagged
+var (
+	secretKeyword = regexp.MustCompile(`(?i)(secret|token|passw(or)?d|pwd|api_?key|access_?key|private_?key|credential|auth)`)
+	entropyToken  = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{20,}`)
+	hexToken      = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	hunkHeader    = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+)
+
+// lockfiles are dependency lockfiles, recognized by file name
+var lockfiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"Gemfile.lock":      true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+	"Pipfile.lock":      true,
+}
+
+// IsLockfile reports whether the file at path is a dependency lockfile.
+// They hold long hashes by design, so they are skipped for entropy checks.
+func IsLockfile(p string) bool {
+	return lockfiles[path.Base(p)]
+}
+
+// SecretFinding is a likely secret on an added line of a diff
+type SecretFinding struct {
+	File string
+	Line int
+	Rule string
+	// Match is the offending text with most of it masked
+	Match string
+}
+
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", f.File, f.Line, f.Rule, f.Match)
+}
+
+// SecretsError reports the findings that blocked a commit
+type SecretsError struct {
+	Findings []SecretFinding
+}
+
+func (e *SecretsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "possible secrets found in staged changes:")
+	for _, f := range e.Findings {
+		fmt.Fprintf(&b, "\n  %s", f)
+	}
+	return b.String()
+}
+
+// SecretAllowlist suppresses known false positives. Each line of the file
+// is a path glob matched against the changed file, or "regex:<pattern>"
+// matched against the suspected secret; # starts a comment.
+type SecretAllowlist struct {
+	paths   []string
+	regexes []*regexp.Regexp
+}
+
+// LoadSecretAllowlist reads the allowlist at path; a missing file yields an
+// empty allowlist
+func LoadSecretAllowlist(file string) (*SecretAllowlist, error) {
+	allow := &SecretAllowlist{}
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return allow, nil
+		}
+		return nil, fmt.Errorf("failed to read secrets allowlist: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if pattern, ok := strings.CutPrefix(line, "regex:"); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid regex: %w", file, n, err)
+			}
+			allow.regexes = append(allow.regexes, re)
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid glob: %w", file, n, err)
+		}
+		allow.paths = append(allow.paths, line)
+	}
+	return allow, scanner.Err()
+}
+
+func (a *SecretAllowlist) allows(file, match string) bool {
+	if a == nil {
+		return false
+	}
+	for _, glob := range a.paths {
+		if ok, _ := path.Match(glob, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(glob, path.Base(file)); ok {
+			return true
+		}
+	}
+	for _, re := range a.regexes {
+		if re.MatchString(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanDiffForSecrets inspects the added lines of a unified diff for known
+// token formats and high-entropy values assigned to secret-looking names
+func ScanDiffForSecrets(diff string, allow *SecretAllowlist) []SecretFinding {
+	var findings []SecretFinding
+	var file string
+	line := 0
+	// inHeader is set between "diff --git" and the first hunk, the only
+	// place "---" and "+++" are file names rather than changed lines
+	inHeader := false
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			inHeader = true
+			continue
+		case inHeader && strings.HasPrefix(text, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			continue
+		case strings.HasPrefix(text, "@@"):
+			inHeader = false
+			if m := hunkHeader.FindStringSubmatch(text); m != nil {
+				line, _ = strconv.Atoi(m[1])
+			}
+			continue
+		case inHeader:
+			continue
+		case strings.HasPrefix(text, "+"):
+			findings = append(findings, scanLine(file, line, text[1:], allow)...)
+			line++
+		case strings.HasPrefix(text, " "):
+			line++
+		}
+	}
+	return findings
+}
+
+func scanLine(file string, line int, text string, allow *SecretAllowlist) []SecretFinding {
+	if strings.Contains(text, AllowSecretMarker) {
+		return nil
+	}
+
+	var findings []SecretFinding
+	add := func(rule, match string) {
+		if !allow.allows(file, match) {
+			findings = append(findings, SecretFinding{File: file, Line: line, Rule: rule, Match: maskSecret(match)})
+		}
+	}
+
+	matched := false
+	for _, rule := range secretRules {
+		if m := rule.pattern.FindString(text); m != "" {
+			add(rule.name, m)
+			matched = true
+		}
+	}
+	if matched || IsLockfile(file) || !secretKeyword.MatchString(text) {
+		return findings
+	}
+
+	for _, token := range entropyToken.FindAllString(text, -1) {
+		if highEntropy(token) {
+			add("high-entropy string", token)
+			break
+		}
+	}
+	return findings
+}
+
+// highEntropy applies a lower bar to hex strings, whose 16-symbol alphabet
+// caps entropy at 4 bits per character
+func highEntropy(token string) bool {
+	if hexToken.MatchString(token) {
+		return len(token) >= 32 && shannonEntropy(token) >= 3.5
+	}
+	return shannonEntropy(token) >= 4.0
+}
+
+// shannonEntropy returns the bits of entropy per character of s
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len(s))
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// maskSecret keeps enough of a match to identify it without leaking it
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "****" + s[len(s)-2:]
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoChanges is returned when there is nothing staged to commit
+var ErrNoChanges = errors.New("no changes to commit")
+
+// StageOptions selects what Stage adds to the index
+type StageOptions struct {
+	// Pathspecs limits staging to these paths; empty stages everything
+	Pathspecs []string
+	// Exclude lists globs that are never staged, on top of .gitignore
+	Exclude []string
+}
+
+// pathspecArgs renders the pathspecs plus exclude globs using git's
+// pathspec magic, so matching happens exactly as git does it. Excludes are
+// relative to the top of the work tree, and "everything" means the whole
+// tree even when run from a subdirectory.
+func (s StageOptions) pathspecArgs() []string {
+	args := append([]string{}, s.Pathspecs...)
+	if len(args) == 0 {
+		args = append(args, ":/")
+	}
+	for _, glob := range s.Exclude {
+		args = append(args, ":(top,exclude,glob)"+glob)
+	}
+	return args
+}
+
+// StatusEntry is one line of git status --porcelain
+type StatusEntry struct {
+	// Status is the two-letter XY code, e.g. " M", "A ", "??"
+	Status string
+	Path   string
+	// OrigPath is the source of a rename or copy
+	OrigPath string
+}
+
+func (e StatusEntry) String() string {
+	if e.OrigPath != "" {
+		return fmt.Sprintf("%s %s -> %s", e.Status, e.OrigPath, e.Path)
+	}
+	return fmt.Sprintf("%s %s", e.Status, e.Path)
+}
+
+// Status lists changed and untracked files, leaving out anything matched
+// by the exclude globs
+func (o *Operations) Status(ctx context.Context, exclude []string) ([]StatusEntry, error) {
+	args := append([]string{"status", "--porcelain=v1", "-z", "--untracked-files=all", "--"},
+		StageOptions{Exclude: exclude}.pathspecArgs()...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = o.workingDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check git status: %w", err)
+	}
+	return parsePorcelain(string(output)), nil
+}
+
+// parsePorcelain parses NUL-terminated porcelain v1 output, where renames
+// and copies are followed by an extra field holding the original path
+func parsePorcelain(output string) []StatusEntry {
+	var entries []StatusEntry
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if len(field) < 4 {
+			continue
+		}
+		entry := StatusEntry{Status: field[:2], Path: field[3:]}
+		if (entry.Status[0] == 'R' || entry.Status[0] == 'C') && i+1 < len(fields) {
+			i++
+			entry.OrigPath = fields[i]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// StagedFiles lists the paths with staged changes
+func (o *Operations) StagedFiles(ctx context.Context) ([]string, error) {
+	out, err := o.output(ctx, "diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// StagedDiff returns the staged changes, without the logging or unstaged
+// fallback of GetDiff
+func (o *Operations) StagedDiff(ctx context.Context) (string, error) {
+	diff, err := o.output(ctx, "diff", "--cached")
+	if err != nil {
+		return "", fmt.Errorf("failed to read staged changes: %w", err)
+	}
+	return diff, nil
+}
@@ -3,10 +3,12 @@ package github
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
-	"os"
+	"strings"
 
 	"github.com/google/go-github/v57/github"
+	"github.com/saint/ghquick/internal/cache"
 	"github.com/saint/ghquick/internal/log"
 	"golang.org/x/oauth2"
 )
@@ -14,70 +16,208 @@ import (
 type Client struct {
 	client *github.Client
 	logger *log.Logger
+	user   *github.User
+	// host keys cached repositories, so Enterprise and github.com
+	// repositories with the same name don't collide
+	host        string
+	repos       *cache.RepoStore
+	conditional *conditionalTransport
 }
 
-func NewClient(token string, debug bool) *Client {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(context.Background(), ts)
-	return &Client{
-		client: github.NewClient(tc),
-		logger: log.New(debug),
+// NewClient creates an API client for host, which is github.com or the
+// hostname of a GitHub Enterprise Server
+func NewClient(token, host string, debug bool) (*Client, error) {
+	logger := log.New(debug)
+	conditional := &conditionalTransport{base: http.DefaultTransport, logger: logger}
+	tc := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+			Base:   newRateLimitTransport(conditional, logger),
+		},
+	}
+
+	client := github.NewClient(tc)
+	if host == "" {
+		host = "github.com"
+	}
+	if host != "github.com" {
+		baseURL := fmt.Sprintf("https://%s/", host)
+		var err error
+		client, err = client.WithEnterpriseURLs(baseURL, baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub Enterprise host %q: %w", host, err)
+		}
 	}
+
+	return &Client{
+		client:      client,
+		logger:      logger,
+		host:        strings.ToLower(host),
+		conditional: conditional,
+	}, nil
 }
 
 func isNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+func hasStatus(err error, statusCode int) bool {
 	if err == nil {
 		return false
 	}
 	if githubErr, ok := err.(*github.ErrorResponse); ok {
-		return githubErr.Response.StatusCode == http.StatusNotFound
+		return githubErr.Response.StatusCode == statusCode
 	}
 	return false
 }
 
-func (c *Client) EnsureRepositoryExists(ctx context.Context, name string, private bool) error {
-	c.logger.Step("Checking if repository exists...")
-	username := os.Getenv("GITHUB_USERNAME")
+// authenticatedUser returns the user the token belongs to, fetching it once
+func (c *Client) authenticatedUser(ctx context.Context) (*github.User, error) {
+	if c.user != nil {
+		return c.user, nil
+	}
+	user, _, err := c.client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authenticated user: %w", err)
+	}
+	c.user = user
+	return user, nil
+}
+
+// Repo describes the GitHub repository being pushed to
+type Repo struct {
+	Owner         string
+	Name          string
+	CloneURL      string
+	DefaultBranch string
+	// Visibility is public, private or internal
+	Visibility string
+	// Created is set when the repository was created by this call
+	Created bool
+}
+
+func newRepo(repo *github.Repository, created bool) *Repo {
+	return &Repo{
+		Owner:         repo.GetOwner().GetLogin(),
+		Name:          repo.GetName(),
+		CloneURL:      repo.GetCloneURL(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Visibility:    visibilityOf(repo),
+		Created:       created,
+	}
+}
+
+// EnsureRepositoryExists looks up owner/name and creates it if missing.
+// When owner isn't the authenticated user the repository is created in
+// that organization. visibility (public, private or internal) only
+// applies at creation; empty leaves it to GitHub's default. Existing
+// repositories are never modified, see SetVisibility.
+func (c *Client) EnsureRepositoryExists(ctx context.Context, owner, name, visibility string) (*Repo, error) {
+	c.logger.Step("Checking if repository %s/%s exists...", owner, name)
 
 	// Try to get the repository first
-	repo, _, err := c.client.Repositories.Get(ctx, username, name)
+	existing, err := c.lookupRepository(ctx, owner, name)
 	if err == nil {
-		c.logger.Info("Repository exists, will append changes")
-		// Update repository settings if needed
-		if repo.GetPrivate() != private {
-			c.logger.Step("Updating repository visibility...")
-			repo.Private = github.Bool(private)
-			_, _, err = c.client.Repositories.Edit(ctx, username, name, repo)
-			if err != nil {
-				c.logger.Error("Failed to update repository visibility")
-				return fmt.Errorf("failed to update repository: %w", err)
-			}
-			c.logger.Success("Repository visibility updated")
-		}
-		return nil
+		c.logger.Info("Repository exists (%s), will append changes", existing.Visibility)
+		return existing, nil
 	}
 
 	// Only create if repository doesn't exist
 	if isNotFound(err) {
-		c.logger.Step("Repository doesn't exist, creating new repository: %s", name)
-		repo = &github.Repository{
+		user, err := c.authenticatedUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// An empty org creates the repository for the authenticated user
+		org := ""
+		if !strings.EqualFold(owner, user.GetLogin()) {
+			org = owner
+		}
+
+		c.logger.Step("Repository doesn't exist, creating new repository: %s/%s", owner, name)
+		repo := &github.Repository{
 			Name:     github.String(name),
-			Private:  github.Bool(private),
 			AutoInit: github.Bool(false),
 		}
+		switch visibility {
+		case "public", "private":
+			repo.Private = github.Bool(visibility == "private")
+		case "internal":
+			repo.Visibility = github.String(visibility)
+		}
 
-		_, _, err = c.client.Repositories.Create(ctx, "", repo)
+		repo, _, err = c.client.Repositories.Create(ctx, org, repo)
 		if err != nil {
 			c.logger.Error("Failed to create repository")
-			return fmt.Errorf("failed to create repository: %w", err)
+			return nil, createError(err, org, owner, name)
 		}
 		c.logger.Success("Repository created successfully")
-		return nil
+		c.storeRepo(owner, name, c.infoOf(repo))
+		return newRepo(repo, true), nil
 	}
 
 	// If we get here, it's an unexpected error
 	c.logger.Error("Failed to check repository")
-	return fmt.Errorf("failed to check repository: %w", err)
+	return nil, fmt.Errorf("failed to check repository: %w", err)
+}
+
+// visibilityOf reads the visibility field, which older Enterprise Server
+// versions omit, falling back to the private flag
+func visibilityOf(repo *github.Repository) string {
+	if v := repo.GetVisibility(); v != "" {
+		return v
+	}
+	if repo.GetPrivate() {
+		return "private"
+	}
+	return "public"
+}
+
+// SetVisibility changes the visibility of an existing repository
+func (c *Client) SetVisibility(ctx context.Context, owner, name, visibility string) error {
+	c.logger.Step("Updating repository visibility to %s...", visibility)
+	repo, _, err := c.client.Repositories.Edit(ctx, owner, name, &github.Repository{
+		Visibility: github.String(visibility),
+	})
+	if err != nil {
+		c.logger.Error("Failed to update repository visibility")
+		return fmt.Errorf("failed to update repository visibility: %w", err)
+	}
+	c.storeRepo(owner, name, c.infoOf(repo))
+	c.logger.Success("Repository visibility updated")
+	return nil
+}
+
+// createError explains the common reasons creating a repository fails
+func createError(err error, org, owner, name string) error {
+	switch {
+	case org != "" && (hasStatus(err, http.StatusForbidden) || hasStatus(err, http.StatusNotFound)):
+		return fmt.Errorf("token cannot create repositories in organization %s: it needs the repo scope "+
+			"(or Administration write access for fine-grained tokens), the org must allow members to create "+
+			"repositories, and any SSO authorization must be granted: %w", org, err)
+	case hasStatus(err, http.StatusUnprocessableEntity) && strings.Contains(err.Error(), "already exists"):
+		return fmt.Errorf("repository %s/%s already exists but this token cannot see it; "+
+			"check that the token has access to %s's private repositories: %w", owner, name, owner, err)
+	}
+	return fmt.Errorf("failed to create repository: %w", err)
+}
+
+// AuthenticatedIdentity returns the commit identity for the token's owner:
+// their display name (or login) and the host's noreply address
+// (users.noreply.<host>), which keeps the real email private
+func (c *Client) AuthenticatedIdentity(ctx context.Context) (name, email string, err error) {
+	user, err := c.authenticatedUser(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	name = user.GetName()
+	if name == "" {
+		name = user.GetLogin()
+	}
+	domain := c.host
+	if h, _, err := net.SplitHostPort(domain); err == nil {
+		domain = h
+	}
+	email = fmt.Sprintf("%d+%s@users.noreply.%s", user.GetID(), user.GetLogin(), domain)
+	return name, email, nil
 }
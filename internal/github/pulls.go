@@ -0,0 +1,113 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// PullRequest is a pull request opened (or found) by ghquick
+type PullRequest struct {
+	Number int
+	URL    string
+	Title  string
+	Draft  bool
+	// Existing is set when an open pull request for the head was reused
+	Existing bool
+}
+
+// PullRequestOptions describes a pull request to open
+type PullRequestOptions struct {
+	Title string
+	Body  string
+	// Head is the branch with the changes, Base the branch to merge into
+	Head      string
+	Base      string
+	Draft     bool
+	Reviewers []string
+	Labels    []string
+}
+
+func newPullRequest(pr *github.PullRequest, existing bool) *PullRequest {
+	return &PullRequest{
+		Number:   pr.GetNumber(),
+		URL:      pr.GetHTMLURL(),
+		Title:    pr.GetTitle(),
+		Draft:    pr.GetDraft(),
+		Existing: existing,
+	}
+}
+
+// GetRepository looks up an existing repository without creating it
+func (c *Client) GetRepository(ctx context.Context, owner, name string) (*Repo, error) {
+	repo, err := c.lookupRepository(ctx, owner, name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("repository %s/%s not found", owner, name)
+		}
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+	return repo, nil
+}
+
+// FindOpenPullRequest returns the open pull request from head in owner's
+// repository, or nil if there is none
+func (c *Client) FindOpenPullRequest(ctx context.Context, owner, name, head string) (*PullRequest, error) {
+	prs, _, err := c.client.PullRequests.List(ctx, owner, name, &github.PullRequestListOptions{
+		State: "open",
+		Head:  owner + ":" + head,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return newPullRequest(prs[0], true), nil
+}
+
+// CreatePullRequest opens a pull request, then requests reviewers and
+// applies labels. Reviewers of the form org/team are requested as teams.
+func (c *Client) CreatePullRequest(ctx context.Context, owner, name string, opts PullRequestOptions) (*PullRequest, error) {
+	c.logger.Step("Creating pull request %s -> %s...", opts.Head, opts.Base)
+	pr, _, err := c.client.PullRequests.Create(ctx, owner, name, &github.NewPullRequest{
+		Title: github.String(opts.Title),
+		Body:  github.String(opts.Body),
+		Head:  github.String(opts.Head),
+		Base:  github.String(opts.Base),
+		Draft: github.Bool(opts.Draft),
+	})
+	if err != nil {
+		c.logger.Error("Failed to create pull request")
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	c.logger.Success("Pull request #%d created", pr.GetNumber())
+
+	if len(opts.Reviewers) > 0 {
+		var req github.ReviewersRequest
+		for _, reviewer := range opts.Reviewers {
+			if _, team, ok := strings.Cut(reviewer, "/"); ok {
+				req.TeamReviewers = append(req.TeamReviewers, team)
+			} else {
+				req.Reviewers = append(req.Reviewers, reviewer)
+			}
+		}
+		if _, _, err := c.client.PullRequests.RequestReviewers(ctx, owner, name, pr.GetNumber(), req); err != nil {
+			c.logger.Error("Failed to request reviewers")
+			return nil, fmt.Errorf("pull request #%d created but requesting reviewers failed: %w", pr.GetNumber(), err)
+		}
+		c.logger.Debug("Requested reviewers: %s", strings.Join(opts.Reviewers, ", "))
+	}
+
+	if len(opts.Labels) > 0 {
+		if _, _, err := c.client.Issues.AddLabelsToIssue(ctx, owner, name, pr.GetNumber(), opts.Labels); err != nil {
+			c.logger.Error("Failed to add labels")
+			return nil, fmt.Errorf("pull request #%d created but adding labels failed: %w", pr.GetNumber(), err)
+		}
+		c.logger.Debug("Added labels: %s", strings.Join(opts.Labels, ", "))
+	}
+
+	return newPullRequest(pr, false), nil
+}
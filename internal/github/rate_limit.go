@@ -0,0 +1,314 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/saint/ghquick/internal/log"
+)
+
+const (
+	// maxRateLimitWait is the longest a request waits for a rate limit to
+	// reset before giving up; primary limits reset hourly, so in practice
+	// only secondary limits and the last minutes of a window are waited out
+	maxRateLimitWait = 2 * time.Minute
+	// secondaryRetries bounds how often one request is retried after
+	// hitting a secondary rate limit
+	secondaryRetries = 3
+	// defaultSecondaryWait is used when a secondary limit names no
+	// Retry-After, as GitHub's docs advise
+	defaultSecondaryWait = time.Minute
+	// lowRateLimit warns once fewer than this fraction of requests remain
+	lowRateLimit = 0.1
+)
+
+// RateLimit is the quota for one API resource
+type RateLimit struct {
+	// Resource is core, search or graphql
+	Resource  string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitError reports an exhausted rate limit that resets too late to
+// wait for
+type RateLimitError struct {
+	RateLimit
+	// Secondary is set for GitHub's abuse-detection limits, as opposed to
+	// the hourly quota
+	Secondary bool
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Secondary {
+		return fmt.Sprintf("GitHub secondary rate limit hit; retry after %s (in %s)",
+			e.Reset.Format("15:04:05"), time.Until(e.Reset).Round(time.Second))
+	}
+	return fmt.Sprintf("GitHub %s API rate limit of %d requests exhausted until %s (in %s); "+
+		"wait, or authenticate with a token that has a higher limit",
+		e.Resource, e.Limit, e.Reset.Format("15:04"), time.Until(e.Reset).Round(time.Minute))
+}
+
+// rateLimitTransport tracks the X-RateLimit-* headers of every response,
+// warns when a quota runs low, waits out short limits (honoring
+// Retry-After on secondary ones) and reports long ones as RateLimitError
+type rateLimitTransport struct {
+	base   http.RoundTripper
+	logger *log.Logger
+
+	mu     sync.Mutex
+	limits map[string]RateLimit
+	warned map[string]bool
+	// secondaryUntil blocks every request after a secondary limit
+	secondaryUntil time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, logger *log.Logger) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:   base,
+		logger: logger,
+		limits: make(map[string]RateLimit),
+		warned: make(map[string]bool),
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	resource := resourceOf(req)
+	if err := t.waitForQuota(ctx, resource); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		limit, exhausted := t.record(resource, resp)
+
+		secondary, wait := t.limited(resp, exhausted)
+		if !secondary && !exhausted {
+			return resp, nil
+		}
+		if secondary {
+			t.mu.Lock()
+			t.secondaryUntil = time.Now().Add(wait)
+			t.mu.Unlock()
+		}
+		if !secondary && limit.Reset.After(time.Now()) {
+			wait = time.Until(limit.Reset)
+		}
+		if attempt >= secondaryRetries || !canWait(ctx, wait) || !rewindable(req) {
+			resp.Body.Close()
+			return nil, &RateLimitError{
+				RateLimit: RateLimit{Resource: limit.Resource, Limit: limit.Limit, Reset: time.Now().Add(wait)},
+				Secondary: secondary,
+			}
+		}
+		resp.Body.Close()
+
+		t.logger.Warning("GitHub rate limit hit, waiting %s before retrying...", wait.Round(time.Second))
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// waitForQuota blocks until the resource's known quota allows a request,
+// failing straight away when that would take too long
+func (t *rateLimitTransport) waitForQuota(ctx context.Context, resource string) error {
+	t.mu.Lock()
+	limit, known := t.limits[resource]
+	secondaryUntil := t.secondaryUntil
+	t.mu.Unlock()
+
+	if wait := time.Until(secondaryUntil); wait > 0 && resource != "" {
+		if !canWait(ctx, wait) {
+			return &RateLimitError{RateLimit: RateLimit{Resource: resource, Reset: secondaryUntil}, Secondary: true}
+		}
+		t.logger.Warning("Waiting %s for GitHub's secondary rate limit...", wait.Round(time.Second))
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+
+	if resource == "" || !known || limit.Remaining > 0 || !limit.Reset.After(time.Now()) {
+		return nil
+	}
+	wait := time.Until(limit.Reset)
+	if !canWait(ctx, wait) {
+		return &RateLimitError{RateLimit: limit}
+	}
+	t.logger.Warning("GitHub %s rate limit exhausted, waiting %s for it to reset...", resource, wait.Round(time.Second))
+	return sleep(ctx, wait)
+}
+
+// record stores the quota a response reports and warns the first time it
+// runs low. exhausted is set when the response was refused for it.
+func (t *rateLimitTransport) record(resource string, resp *http.Response) (limit RateLimit, exhausted bool) {
+	limit, ok := parseRateLimit(resp.Header)
+	if !ok {
+		return RateLimit{Resource: resource}, false
+	}
+	if limit.Resource == "" {
+		if resource == "" {
+			resource = "core"
+		}
+		limit.Resource = resource
+	}
+
+	t.mu.Lock()
+	t.limits[limit.Resource] = limit
+	warn := !t.warned[limit.Resource] && limit.Limit > 0 &&
+		float64(limit.Remaining) < lowRateLimit*float64(limit.Limit)
+	if warn {
+		t.warned[limit.Resource] = true
+	}
+	t.mu.Unlock()
+
+	if warn {
+		t.logger.Warning("GitHub %s rate limit is low: %d of %d requests left until %s",
+			limit.Resource, limit.Remaining, limit.Limit, limit.Reset.Format("15:04"))
+	}
+	refused := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests
+	return limit, refused && limit.Remaining == 0
+}
+
+// limited reports whether resp is a secondary rate limit and how long to
+// wait before retrying
+func (t *rateLimitTransport) limited(resp *http.Response, exhausted bool) (secondary bool, wait time.Duration) {
+	if exhausted || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return false, 0
+	}
+	retryAfter := resp.Header.Get("Retry-After")
+	if retryAfter == "" && !mentionsSecondaryLimit(resp) {
+		// An ordinary permission error
+		return false, 0
+	}
+	wait = defaultSecondaryWait
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	}
+	return true, wait
+}
+
+// mentionsSecondaryLimit peeks at the error body, leaving it readable
+func mentionsSecondaryLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "secondary rate limit") || strings.Contains(lower, "abuse detection")
+}
+
+// parseRateLimit reads the X-RateLimit-* headers
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, _ := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	return RateLimit{
+		Resource:  h.Get("X-RateLimit-Resource"),
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}, true
+}
+
+// resourceOf guesses which quota a request counts against, for checking
+// it before the response says, or "" for /rate_limit, which counts
+// against none
+func resourceOf(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/api/v3")
+	switch {
+	case path == "/rate_limit":
+		return ""
+	case strings.HasPrefix(path, "/search/"):
+		return "search"
+	case strings.HasSuffix(path, "/graphql"):
+		return "graphql"
+	}
+	return "core"
+}
+
+// canWait reports whether waiting d is reasonable and fits before ctx's
+// deadline
+func canWait(ctx context.Context, d time.Duration) bool {
+	if d > maxRateLimitWait {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(d).After(deadline) {
+		return false
+	}
+	return true
+}
+
+// rewindable reports whether req can be sent again
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RateLimits returns the current quota for the core, search and graphql
+// APIs. Checking doesn't count against any of them.
+func (c *Client) RateLimits(ctx context.Context) ([]RateLimit, error) {
+	limits, _, err := c.client.RateLimits(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limits: %w", err)
+	}
+	var out []RateLimit
+	for _, r := range []struct {
+		resource string
+		rate     *github.Rate
+	}{
+		{"core", limits.Core},
+		{"search", limits.Search},
+		{"graphql", limits.GraphQL},
+	} {
+		if r.rate == nil {
+			continue
+		}
+		out = append(out, RateLimit{
+			Resource:  r.resource,
+			Limit:     r.rate.Limit,
+			Remaining: r.rate.Remaining,
+			Reset:     r.rate.Reset.Time,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package github
+
+import (
+	"context"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/saint/ghquick/internal/cache"
+)
+
+// SetRepoStore makes repository lookups remember what they find in store
+// across invocations. Fresh entries are used without calling the API.
+func (c *Client) SetRepoStore(store *cache.RepoStore) {
+	c.repos = store
+}
+
+// SetResponseStore keeps GET responses in store and revalidates them with
+// conditional requests. Call it before making requests.
+func (c *Client) SetResponseStore(store *cache.Store) {
+	c.conditional.store = store
+}
+
+// cachedRepo returns the stored entry for owner/name and whether it is
+// fresh. The cache is best effort, so failures only miss.
+func (c *Client) cachedRepo(owner, name string) (*cache.RepoInfo, bool) {
+	if c.repos == nil {
+		return nil, false
+	}
+	info, fresh, err := c.repos.Lookup(c.host, owner, name)
+	if err != nil {
+		c.logger.Debug("Repository cache unavailable: %v", err)
+		return nil, false
+	}
+	return info, fresh
+}
+
+// storeRepo records info for owner/name, ignoring cache failures
+func (c *Client) storeRepo(owner, name string, info *cache.RepoInfo) {
+	if c.repos == nil {
+		return
+	}
+	if err := c.repos.Put(c.host, owner, name, info); err != nil {
+		c.logger.Debug("Failed to update repository cache: %v", err)
+	}
+}
+
+// ForgetRepo drops any cached entry for owner/name, for when an operation
+// suggests the cached information is wrong
+func (c *Client) ForgetRepo(owner, name string) {
+	if c.repos == nil {
+		return
+	}
+	if err := c.repos.Delete(c.host, owner, name); err != nil {
+		c.logger.Debug("Failed to update repository cache: %v", err)
+	}
+}
+
+// lookupRepository gets owner/name from the cache or the API. Errors are
+// the API's, so callers can tell a missing repository apart.
+func (c *Client) lookupRepository(ctx context.Context, owner, name string) (*Repo, error) {
+	if cached, fresh := c.cachedRepo(owner, name); fresh {
+		c.logger.Debug("Using cached repository info for %s/%s", owner, name)
+		return repoFromInfo(cached), nil
+	}
+
+	// The response cache, if enabled, turns this into a conditional request
+	repo, _, err := c.client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	c.storeRepo(owner, name, c.infoOf(repo))
+	return newRepo(repo, false), nil
+}
+
+func (c *Client) infoOf(repo *github.Repository) *cache.RepoInfo {
+	return &cache.RepoInfo{
+		Host:          c.host,
+		Owner:         repo.GetOwner().GetLogin(),
+		Name:          repo.GetName(),
+		Remote:        repo.GetCloneURL(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Visibility:    visibilityOf(repo),
+	}
+}
+
+func repoFromInfo(info *cache.RepoInfo) *Repo {
+	return &Repo{
+		Owner:         info.Owner,
+		Name:          info.Name,
+		CloneURL:      info.Remote,
+		DefaultBranch: info.DefaultBranch,
+		Visibility:    info.Visibility,
+	}
+}
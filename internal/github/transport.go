@@ -0,0 +1,210 @@
+package github
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/saint/ghquick/internal/cache"
+	"github.com/saint/ghquick/internal/log"
+)
+
+const (
+	// maxCachedBody skips caching responses too large to be worth keeping
+	maxCachedBody = 1 << 20
+	// maxCachedResponses and maxResponseAge bound the response cache
+	maxCachedResponses = 200
+	maxResponseAge     = 7 * 24 * time.Hour
+)
+
+// cachedResponse is a GET response kept for revalidation
+type cachedResponse struct {
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	StoredAt     time.Time   `json:"stored_at"`
+}
+
+// conditionalTransport makes GET requests conditional on the validators of
+// a previously stored response. GitHub answers 304 Not Modified when
+// nothing changed, which doesn't count against the rate limit, and the
+// stored response is returned in its place. It sits below the oauth2
+// transport so responses are kept apart per token.
+type conditionalTransport struct {
+	base   http.RoundTripper
+	store  *cache.Store
+	logger *log.Logger
+}
+
+func (t *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Callers sending their own validators get the server's answer as is
+	if t.store == nil || req.Method != http.MethodGet ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := responseKey(req)
+	cached := t.lookup(key)
+	if cached != nil {
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		t.logger.Debug("GET %s: not modified, using cached response", req.URL.Path)
+		resp.Body.Close()
+		return cached.response(req, resp), nil
+	case resp.StatusCode == http.StatusOK:
+		return t.save(key, resp), nil
+	}
+	return resp, nil
+}
+
+// responseKey identifies a response by URL, the media type asked for and
+// the credentials that fetched it
+func responseKey(req *http.Request) string {
+	auth := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return req.URL.String() + " " + req.Header.Get("Accept") + " " + hex.EncodeToString(auth[:8])
+}
+
+func (t *conditionalTransport) lookup(key string) *cachedResponse {
+	var cached *cachedResponse
+	err := t.store.Read(func(entries map[string]json.RawMessage) error {
+		raw, ok := entries[key]
+		if !ok {
+			return nil
+		}
+		var entry cachedResponse
+		if json.Unmarshal(raw, &entry) == nil {
+			cached = &entry
+		}
+		return nil
+	})
+	if err != nil {
+		t.logger.Debug("Response cache unavailable: %v", err)
+		return nil
+	}
+	return cached
+}
+
+// save stores a 200 response that carries a validator and returns it with
+// its body still readable
+func (t *conditionalTransport) save(key string, resp *http.Response) *http.Response {
+	entry := &cachedResponse{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Header:       resp.Header.Clone(),
+		StoredAt:     time.Now(),
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return resp
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+	if err != nil || len(body) > maxCachedBody {
+		// Hand back what was read followed by the rest, or the read error
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), errReader{err}, resp.Body), resp.Body}
+		return resp
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	entry.Body = body
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = t.store.Update(func(entries map[string]json.RawMessage) error {
+			entries[key] = data
+			pruneResponses(entries)
+			return nil
+		})
+	}
+	if err != nil {
+		t.logger.Debug("Failed to update response cache: %v", err)
+	}
+	return resp
+}
+
+// pruneResponses drops expired entries and then the oldest ones beyond
+// maxCachedResponses
+func pruneResponses(entries map[string]json.RawMessage) {
+	type aged struct {
+		key      string
+		storedAt time.Time
+	}
+	var kept []aged
+	for key, raw := range entries {
+		var entry struct {
+			StoredAt time.Time `json:"stored_at"`
+		}
+		if json.Unmarshal(raw, &entry) != nil || time.Since(entry.StoredAt) > maxResponseAge {
+			delete(entries, key)
+			continue
+		}
+		kept = append(kept, aged{key, entry.StoredAt})
+	}
+	if len(kept) <= maxCachedResponses {
+		return
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].storedAt.Before(kept[j].storedAt) })
+	for _, a := range kept[:len(kept)-maxCachedResponses] {
+		delete(entries, a.key)
+	}
+}
+
+// response rebuilds the stored response, with headers the 304 refreshed
+// (rate limit, date, validators) taking precedence
+func (c *cachedResponse) response(req *http.Request, notModified *http.Response) *http.Response {
+	header := c.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	for name, values := range notModified.Header {
+		header[name] = values
+	}
+	header.Set("Content-Length", strconv.Itoa(len(c.Body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// errReader returns err once the reader before it is exhausted
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}